@@ -0,0 +1,105 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"time"
+)
+
+// bench config
+type benchCfg struct {
+	seed       int64
+	count      int
+	cpuprofile string
+	memprofile string
+}
+
+func parseBenchFlags(args []string) benchCfg {
+	var c benchCfg
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	fs.Int64Var(&c.seed, "seed", 1, "Random seed for generator. Defaults to 1")
+	fs.IntVar(&c.count, "count", 100000, "Number of entries to generate, must be positive. Defaults to 100000")
+	fs.StringVar(&c.cpuprofile, "cpuprofile", "", "Filename to write a cpu profile")
+	fs.StringVar(&c.memprofile, "memprofile", "", "Filename to write a heap profile")
+	// ExitOnError makes Parse exit instead of returning an error
+	_ = fs.Parse(args)
+	err := bindEnv(fs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return c
+}
+
+// runBench generates entries to io.Discard and reports throughput and allocations
+func runBench(args []string) error {
+	c := parseBenchFlags(args)
+	// a negative count would generate until interrupted, and rates need rows
+	if c.count <= 0 {
+		return fmt.Errorf("count must be positive, got %d", c.count)
+	}
+	cfg := genCfg{seed: c.seed, count: c.count, format: "csv", lineEnding: "lf"}
+	selected, err := selectColumns(cfg, nil)
+	if err != nil {
+		return err
+	}
+
+	if c.cpuprofile != "" {
+		f, err := os.Create(c.cpuprofile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		err = pprof.StartCPUProfile(f)
+		if err != nil {
+			return err
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	_, err = writeEncoded(io.Discard, cfg, selected)
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	allocs := after.Mallocs - before.Mallocs
+	fmt.Printf("rows: %d\n", c.count)
+	fmt.Printf("elapsed: %v\n", elapsed)
+	fmt.Printf("rows/sec: %.0f\n", float64(c.count)/elapsed.Seconds())
+	fmt.Printf("allocs: %d (%.1f/row)\n", allocs, float64(allocs)/float64(c.count))
+	fmt.Printf("bytes allocated: %d\n", after.TotalAlloc-before.TotalAlloc)
+
+	if c.memprofile != "" {
+		f, err := os.Create(c.memprofile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		runtime.GC()
+		return pprof.WriteHeapProfile(f)
+	}
+	return nil
+}
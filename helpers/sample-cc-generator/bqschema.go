@@ -0,0 +1,283 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	gofakeit "github.com/brianvoe/gofakeit/v6"
+)
+
+// schemaColumns holds the output columns, in order, when generating from a BigQuery schema
+var schemaColumns []string
+
+// piiHints mark a schema column as PII when its name contains one of them
+var piiHints = []string{"card", "cvv", "pin", "ssn", "name", "email", "phone", "account", "address", "birth", "dob"}
+
+// bqField is a column of a BigQuery json schema file
+type bqField struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Mode        string `json:"mode"`
+	Description string `json:"description"`
+}
+
+// normalizeName lowercases name and drops everything but letters and digits,
+// so card_number matches the built-in Card Number column
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// builtinColumn returns the always generated column matching name, if any
+func builtinColumn(name string) (column, bool) {
+	for _, c := range columns {
+		if c.option == "" && normalizeName(c.name) == normalizeName(name) {
+			return c, true
+		}
+	}
+	return column{}, false
+}
+
+// bqKinds maps the BigQuery types and their standard SQL aliases to the column
+// type they are generated and written as
+var bqKinds = map[string]string{
+	"STRING":     typeString,
+	"INT64":      typeInteger,
+	"INTEGER":    typeInteger,
+	"FLOAT64":    typeFloat,
+	"FLOAT":      typeFloat,
+	"NUMERIC":    typeNumeric,
+	"DECIMAL":    typeNumeric,
+	"BIGNUMERIC": typeBigNumeric,
+	"BIGDECIMAL": typeBigNumeric,
+	"BOOL":       typeBoolean,
+	"BOOLEAN":    typeBoolean,
+	"DATE":       "DATE",
+	"DATETIME":   "DATETIME",
+	"TIMESTAMP":  "TIMESTAMP",
+	"TIME":       "TIME",
+}
+
+// validateSchema checks the columns of a schema file can be generated
+func validateSchema(filename string, fields []bqField) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("schema %s has no columns", filename)
+	}
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if strings.TrimSpace(f.Name) == "" {
+			return fmt.Errorf("schema %s has a column without a name", filename)
+		}
+		// BigQuery column names are case insensitive
+		key := strings.ToLower(f.Name)
+		if seen[key] {
+			return fmt.Errorf("column %q is in schema %s more than once", f.Name, filename)
+		}
+		seen[key] = true
+		if _, ok := bqKinds[strings.ToUpper(f.Type)]; !ok {
+			return fmt.Errorf("column %q has unsupported type %q", f.Name, f.Type)
+		}
+		switch strings.ToUpper(f.Mode) {
+		case "", "NULLABLE", "REQUIRED":
+		case "REPEATED":
+			return fmt.Errorf("column %q is repeated, which is not supported", f.Name)
+		default:
+			return fmt.Errorf("column %q has invalid mode %q", f.Name, f.Mode)
+		}
+	}
+	return nil
+}
+
+// schemaGenerator picks a faker generator for a schema column from its name and type
+func schemaGenerator(f bqField) (func(faker *gofakeit.Faker, row *Context) string, error) {
+	name := normalizeName(f.Name)
+	switch bqKinds[strings.ToUpper(f.Type)] {
+	case typeString:
+		hints := []struct {
+			hint string
+			fn   func(faker *gofakeit.Faker) string
+		}{
+			{"email", (*gofakeit.Faker).Email},
+			{"phone", (*gofakeit.Faker).Phone},
+			{"firstname", (*gofakeit.Faker).FirstName},
+			{"lastname", (*gofakeit.Faker).LastName},
+			{"name", (*gofakeit.Faker).Name},
+			{"city", (*gofakeit.Faker).City},
+			{"state", (*gofakeit.Faker).State},
+			{"country", (*gofakeit.Faker).Country},
+			{"zip", (*gofakeit.Faker).Zip},
+			{"postal", (*gofakeit.Faker).Zip},
+			{"address", (*gofakeit.Faker).Street},
+			{"street", (*gofakeit.Faker).Street},
+			{"company", (*gofakeit.Faker).Company},
+			{"merchant", (*gofakeit.Faker).Company},
+		}
+		if strings.HasSuffix(name, "id") {
+			return func(faker *gofakeit.Faker, row *Context) string { return faker.UUID() }, nil
+		}
+		for _, h := range hints {
+			if strings.Contains(name, h.hint) {
+				fn := h.fn
+				return func(faker *gofakeit.Faker, row *Context) string { return fn(faker) }, nil
+			}
+		}
+		return func(faker *gofakeit.Faker, row *Context) string { return faker.Word() }, nil
+	case typeInteger:
+		return func(faker *gofakeit.Faker, row *Context) string {
+			return strconv.Itoa(faker.Number(0, 999999))
+		}, nil
+	case typeFloat, typeNumeric, typeBigNumeric:
+		return func(faker *gofakeit.Faker, row *Context) string {
+			return strconv.FormatFloat(faker.Price(0, 999999), 'f', 2, 64)
+		}, nil
+	case typeBoolean:
+		return func(faker *gofakeit.Faker, row *Context) string {
+			return strconv.FormatBool(faker.Bool())
+		}, nil
+	case "DATE":
+		return func(faker *gofakeit.Faker, row *Context) string {
+			return schemaTime(faker).Format("2006-01-02")
+		}, nil
+	case "DATETIME":
+		return func(faker *gofakeit.Faker, row *Context) string {
+			return schemaTime(faker).Format("2006-01-02T15:04:05")
+		}, nil
+	case "TIMESTAMP":
+		return func(faker *gofakeit.Faker, row *Context) string {
+			return schemaTime(faker).Format(time.RFC3339)
+		}, nil
+	case "TIME":
+		return func(faker *gofakeit.Faker, row *Context) string {
+			return schemaTime(faker).Format("15:04:05")
+		}, nil
+	default:
+		return nil, fmt.Errorf("column %q has unsupported type %q", f.Name, f.Type)
+	}
+}
+
+// schemaTime generates a time within the card issue years
+func schemaTime(faker *gofakeit.Faker) time.Time {
+	return faker.DateRange(
+		time.Date(minIssueYear, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(maxIssueYear, time.January, 1, 0, 0, 0, 0, time.UTC),
+	).UTC()
+}
+
+// builtinGenerator reuses the values of built-in column c for schema column f.
+// The types have to match, except that any column can be a STRING and the
+// MM/YYYY months can be a DATE on the first of the month.
+func builtinGenerator(c column, f bqField) (func(faker *gofakeit.Faker, row *Context) string, error) {
+	name := c.name
+	kind := bqKinds[strings.ToUpper(f.Type)]
+	switch {
+	case kind == c.kind || kind == typeString:
+		return func(faker *gofakeit.Faker, row *Context) string { return row.Value(name) }, nil
+	case kind == "DATE" && (name == "Issue Date" || name == "Expiry Date"):
+		return func(faker *gofakeit.Faker, row *Context) string {
+			t, err := time.Parse("01/2006", row.Value(name))
+			if err != nil {
+				return ""
+			}
+			return t.Format("2006-01-02")
+		}, nil
+	default:
+		return nil, fmt.Errorf("column %q is %s but the built-in column %q is %s", f.Name, kind, name, c.kind)
+	}
+}
+
+// loadBQSchema makes the columns of a BigQuery json schema file the output columns.
+// Columns matching a built-in column by name reuse its values when the types
+// agree, the rest are generated from their type. Built-in columns are still
+// generated so seeds keep producing the same cards.
+func loadBQSchema(filename string, cfg genCfg) error {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var fields []bqField
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		return fmt.Errorf("parsing schema %s: %v", filename, err)
+	}
+	err = validateSchema(filename, fields)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		if i := columnIndex(f.Name); i != -1 {
+			if !cfg.enabled(columns[i].option) {
+				return fmt.Errorf("column %q in schema %s is not enabled", f.Name, filename)
+			}
+			if kind := bqKinds[strings.ToUpper(f.Type)]; kind != columns[i].kind {
+				return fmt.Errorf("column %q in schema %s is %s but the built-in column is %s", f.Name, filename, kind, columns[i].kind)
+			}
+			schemaColumns = append(schemaColumns, f.Name)
+			continue
+		}
+
+		var generate func(faker *gofakeit.Faker, row *Context) string
+		pii := false
+		if c, ok := builtinColumn(f.Name); ok {
+			generate, err = builtinGenerator(c, f)
+			if err != nil {
+				return fmt.Errorf("%v in schema %s", err, filename)
+			}
+			pii = c.pii
+		} else {
+			generate, err = schemaGenerator(f)
+			if err != nil {
+				return err
+			}
+			for _, hint := range piiHints {
+				if strings.Contains(normalizeName(f.Name), hint) {
+					pii = true
+				}
+			}
+		}
+		err = registerField(field{f.Name, generate}, bqKinds[strings.ToUpper(f.Type)], f.Description, pii)
+		if err != nil {
+			return err
+		}
+		schemaColumns = append(schemaColumns, f.Name)
+	}
+	return nil
+}
+
+// writeBQSchema writes a BigQuery json schema of the selected columns
+func writeBQSchema(filename string, selected []int) error {
+	fields := make([]bqField, 0, len(selected))
+	for _, i := range selected {
+		c := columns[i]
+		fields = append(fields, bqField{Name: snakeName(c.name), Type: c.kind, Mode: "NULLABLE", Description: c.description})
+	}
+	return writeFile(filename, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(fields)
+	})
+}
@@ -0,0 +1,175 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeSchema(t *testing.T, schema string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "schema.json")
+	err := os.WriteFile(filename, []byte(schema), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestBQSchema(t *testing.T) {
+	schema := writeSchema(t, `[
+		{"name": "card_number", "type": "STRING"},
+		{"name": "visits", "type": "INT64", "mode": "REQUIRED"},
+		{"name": "score", "type": "FLOAT64"},
+		{"name": "amount", "type": "NUMERIC"},
+		{"name": "total", "type": "BIGNUMERIC"},
+		{"name": "active", "type": "BOOL"}
+	]`)
+	dir := t.TempDir()
+	catalog := filepath.Join(dir, "catalog.json")
+	filename := generateFile(t, "-from-bq-schema", schema, "-format", "ndjson", "-count", "5",
+		"-truncate-pan", "-catalog", catalog)
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d rows, want 5", len(lines))
+	}
+	for _, line := range lines {
+		var row map[string]interface{}
+		err = json.Unmarshal([]byte(line), &row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, want := range map[string]string{
+			"card_number": "string",
+			"visits":      "float64",
+			"score":       "float64",
+			"amount":      "float64",
+			"total":       "float64",
+			"active":      "bool",
+		} {
+			var got string
+			switch row[name].(type) {
+			case string:
+				got = "string"
+			case float64:
+				got = "float64"
+			case bool:
+				got = "bool"
+			}
+			if got != want {
+				t.Errorf("%s = %#v, want a %s", name, row[name], want)
+			}
+		}
+	}
+
+	b, err = os.ReadFile(catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []struct {
+		Name       string `json:"name"`
+		Type       string `json:"type"`
+		Protection string `json:"protection"`
+	}
+	err = json.Unmarshal(b, &entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := map[string]string{
+		"card_number": typeString,
+		"visits":      typeInteger,
+		"score":       typeFloat,
+		"amount":      typeNumeric,
+		"total":       typeBigNumeric,
+		"active":      typeBoolean,
+	}
+	for _, e := range entries {
+		if e.Type != types[e.Name] {
+			t.Errorf("catalog type of %s = %s, want %s", e.Name, e.Type, types[e.Name])
+		}
+		want := protectionNone
+		if e.Name == "card_number" {
+			want = protectionMasked
+		}
+		if e.Protection != want {
+			t.Errorf("catalog protection of %s = %s, want %s", e.Name, e.Protection, want)
+		}
+	}
+}
+
+func TestBQSchemaBuiltinDate(t *testing.T) {
+	schema := writeSchema(t, `[
+		{"name": "issue_date", "type": "DATE"},
+		{"name": "expiry_date", "type": "STRING"},
+		{"name": "credit_limit", "type": "INT64"}
+	]`)
+	header, rows := generateCSV(t, "-from-bq-schema", schema, "-count", "20")
+	if strings.Join(header, ",") != "issue_date,expiry_date,credit_limit" {
+		t.Fatalf("got header %v", header)
+	}
+	for _, row := range rows {
+		issued, err := time.Parse("2006-01-02", row["issue_date"])
+		if err != nil || issued.Day() != 1 {
+			t.Errorf("issue_date %q isn't the first of a month as YYYY-MM-DD", row["issue_date"])
+		}
+		_, err = time.Parse("01/2006", row["expiry_date"])
+		if err != nil {
+			t.Errorf("expiry_date %q isn't MM/YYYY", row["expiry_date"])
+		}
+		_, err = strconv.Atoi(row["credit_limit"])
+		if err != nil {
+			t.Errorf("credit_limit %q isn't an integer", row["credit_limit"])
+		}
+	}
+}
+
+func TestBQSchemaErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{"empty", `[]`, "no columns"},
+		{"unnamed", `[{"name": "", "type": "STRING"}]`, "without a name"},
+		{"duplicate", `[{"name": "notes", "type": "STRING"}, {"name": "Notes", "type": "STRING"}]`, "more than once"},
+		{"duplicate same case", `[{"name": "extra", "type": "STRING"}, {"name": "extra", "type": "INT64"}]`, "more than once"},
+		{"repeated", `[{"name": "tags", "type": "STRING", "mode": "REPEATED"}]`, "repeated"},
+		{"mode", `[{"name": "tags", "type": "STRING", "mode": "OPTIONAL"}]`, "invalid mode"},
+		{"type", `[{"name": "location", "type": "GEOGRAPHY"}]`, "unsupported type"},
+		{"builtin type", `[{"name": "credit_limit", "type": "BOOL"}]`, "built-in column"},
+		{"builtin exact type", `[{"name": "Credit Limit", "type": "STRING"}]`, "built-in column"},
+		{"builtin date", `[{"name": "card_pin", "type": "DATE"}]`, "built-in column"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := parseArgs(t, "-filename", filepath.Join(t.TempDir(), "data.csv"),
+				"-from-bq-schema", writeSchema(t, tc.schema))
+			err := run(cfg)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("got error %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
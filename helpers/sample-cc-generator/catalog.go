@@ -0,0 +1,317 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	typeString  = "STRING"
+	typeInteger = "INTEGER"
+	typeBoolean = "BOOLEAN"
+	// typeFloat, typeNumeric and typeBigNumeric are only used by from-bq-schema columns
+	typeFloat      = "FLOAT"
+	typeNumeric    = "NUMERIC"
+	typeBigNumeric = "BIGNUMERIC"
+
+	// protectionNone marks a column written as plaintext
+	protectionNone = "none"
+	// protectionMasked marks a column with part of its value removed
+	protectionMasked = "masked"
+
+	// optionACH enables the ACH routing and account number columns
+	optionACH = "ach"
+	// optionDispute enables the disputed column
+	optionDispute = "dispute"
+	// optionUUID enables the customer uuid column
+	optionUUID = "uuid"
+	// optionSplitName enables the first and last name columns
+	optionSplitName = "split-name"
+	// optionDueDate enables the due date column
+	optionDueDate = "due-date"
+	// optionBalance enables the balance column
+	optionBalance = "balance"
+	// optionDuplicate enables the duplicate flag column
+	optionDuplicate = "duplicate"
+	// optionHierarchy enables the parent account columns
+	optionHierarchy = "accounts-hierarchy"
+	// optionLastFour enables the last four digits column
+	optionLastFour = "last-four"
+	// optionCountries enables the residency and nationality columns
+	optionCountries = "countries"
+	// optionNotes enables the notes columns
+	optionNotes = "notes"
+)
+
+// column describes a csv column. columns is the source of truth for the csv
+// header and the catalog, each column is generated by the FieldGenerator of the
+// same name.
+type column struct {
+	name        string
+	kind        string
+	description string
+	pii         bool
+	// option names the flag that enables the column, empty if always emitted
+	option string
+}
+
+var columns = []column{
+	{"Card Type Code", typeString, "Two letter code of the card network", false, ""},
+	{"Card Type Full Name", typeString, "Name of the card network", false, ""},
+	{"Issuing Bank", typeString, "Bank that issued the card", false, ""},
+	{"Card Number", typeString, "Primary account number", true, ""},
+	{"Card Holder's Name", typeString, "Name of the card holder", true, ""},
+	{"CVV/CVV2", typeString, "Card verification value", true, ""},
+	{"Issue Date", typeString, "Issue month formatted as MM/YYYY", false, ""},
+	{"Expiry Date", typeString, "Expiry month formatted as MM/YYYY", false, ""},
+	{"Billing Date", typeInteger, "Day of the month the card is billed", false, ""},
+	{"Card PIN", typeString, "Four digit personal identification number", true, ""},
+	{"Credit Limit", typeInteger, "Credit limit of the card", false, ""},
+	{"Routing Number", typeString, "ABA routing number with a valid check digit", false, optionACH},
+	{"Account Number", typeString, "ACH account number", true, optionACH},
+	{"Customer UUID", typeString, "UUID v5 derived from the card holder's name and customer, the same for all cards of a customer", false, optionUUID},
+	{"First Name", typeString, "First name of the card holder", true, optionSplitName},
+	{"Last Name", typeString, "Last name of the card holder", true, optionSplitName},
+	{"Due Date", typeString, "Payment due date of the first statement formatted as MM/DD/YYYY", false, optionDueDate},
+	{"Disputed", typeBoolean, "Whether the card has a chargeback or dispute", false, optionDispute},
+	{"Balance", typeInteger, "Amount owed, above the credit limit for over-limit accounts", false, optionBalance},
+	{"Is Duplicate", typeBoolean, "Whether the row is a copy of an earlier row", false, optionDuplicate},
+	{"Parent Account ID", typeString, "Corporate account the card is a child of", false, optionHierarchy},
+	{"Parent Credit Limit", typeInteger, "Credit limit of the parent account, split evenly between its cards", false, optionHierarchy},
+	{"Notes", typeString, "Free-text notes, some embedding an email address, US SSN or phone number", true, optionNotes},
+	{"Notes PII", typeBoolean, "Whether the notes embed PII", false, optionNotes},
+	{"Last Four", typeString, "Last four digits of the card number, matching it even when it is truncated", false, optionLastFour},
+	{"Residency Country", typeString, "Two letter code of the country the card holder lives in", true, optionCountries},
+	{"Nationality", typeString, "Two letter code of the card holder's nationality, matching the residency country except for expats", true, optionCountries},
+}
+
+// profiles are curated column sets selected with -profile. Optional columns
+// listed in a profile are enabled by it.
+var profiles = map[string][]string{
+	"pan-only": {"Card Number"},
+	"full-card": {"Card Type Code", "Card Type Full Name", "Issuing Bank", "Card Number", "Card Holder's Name",
+		"CVV/CVV2", "Issue Date", "Expiry Date", "Billing Date", "Card PIN", "Credit Limit"},
+	"kyc": {"Customer UUID", "Card Holder's Name", "First Name", "Last Name"},
+}
+
+// profileNames returns the names of the profiles
+func profileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// columnNames returns the header names of cols
+func columnNames(cols []column) []string {
+	names := make([]string, 0, len(cols))
+	for _, c := range cols {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// columnIndex returns the index of the named column, or -1 if it doesn't exist
+func columnIndex(name string) int {
+	for i, c := range columns {
+		if c.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// selectColumns returns the indexes of the columns enabled in cfg and not named in exclude
+func selectColumns(cfg genCfg, exclude []string) ([]int, error) {
+	excluded := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		name = strings.TrimSpace(name)
+		if columnIndex(name) == -1 {
+			return nil, fmt.Errorf("unknown column %q in exclude-fields", name)
+		}
+		excluded[name] = true
+	}
+	var idx []int
+	if len(schemaColumns) > 0 {
+		for _, name := range schemaColumns {
+			if !excluded[name] {
+				idx = append(idx, columnIndex(name))
+			}
+		}
+	} else {
+		inProfile := make(map[string]bool)
+		for _, name := range profiles[cfg.profile] {
+			inProfile[name] = true
+		}
+		for i, c := range columns {
+			if cfg.profile != "" && !inProfile[c.name] {
+				continue
+			}
+			if cfg.enabled(c.option) && !excluded[c.name] {
+				idx = append(idx, i)
+			}
+		}
+	}
+	if len(idx) == 0 {
+		return nil, fmt.Errorf("exclude-fields removes every column")
+	}
+	if len(columnsOrder) > 0 {
+		return orderColumns(idx, columnsOrder)
+	}
+	return idx, nil
+}
+
+// orderColumns moves the columns named in order to the front in that order,
+// the remaining columns follow in their current order
+func orderColumns(idx []int, order []string) ([]int, error) {
+	ordered := make([]int, 0, len(idx))
+	placed := make(map[int]bool, len(order))
+	for _, name := range order {
+		i := columnIndex(name)
+		if i == -1 {
+			return nil, fmt.Errorf("unknown column %q in columns-order", name)
+		}
+		for _, j := range idx {
+			if j == i && !placed[i] {
+				ordered = append(ordered, i)
+				placed[i] = true
+			}
+		}
+	}
+	for _, i := range idx {
+		if !placed[i] {
+			ordered = append(ordered, i)
+		}
+	}
+	return ordered, nil
+}
+
+// selectValues returns the values at the given column indexes
+func selectValues(values []string, idx []int) []string {
+	selected := make([]string, 0, len(idx))
+	for _, i := range idx {
+		selected = append(selected, values[i])
+	}
+	return selected
+}
+
+// catalog entry
+type catalogEntry struct {
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	Description string     `json:"description"`
+	Example     typedValue `json:"example"`
+	Protection  string     `json:"protection"`
+	PII         bool       `json:"pii"`
+}
+
+// typedValue is a csv value written to json as a value of its column's type,
+// so numbers and booleans aren't quoted
+type typedValue struct {
+	kind  string
+	value string
+}
+
+func (v typedValue) MarshalJSON() ([]byte, error) {
+	switch v.kind {
+	case typeInteger:
+		if n, err := strconv.ParseInt(v.value, 10, 64); err == nil {
+			return []byte(strconv.FormatInt(n, 10)), nil
+		}
+	case typeFloat, typeNumeric, typeBigNumeric:
+		// formatting the parsed number turns 007, +5, .5 or 0x1p3 into valid json
+		if f, err := strconv.ParseFloat(v.value, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return []byte(strconv.FormatFloat(f, 'g', -1, 64)), nil
+		}
+	case typeBoolean:
+		if b, err := strconv.ParseBool(v.value); err == nil {
+			return json.Marshal(b)
+		}
+	}
+	return json.Marshal(v.value)
+}
+
+func (c catalogEntry) strSlice() []string {
+	return []string{
+		c.Name,
+		c.Type,
+		c.Description,
+		c.Example.value,
+		c.Protection,
+		strconv.FormatBool(c.PII),
+	}
+}
+
+// protection returns how the values of c are protected in the output
+func protection(cfg genCfg, c column) string {
+	// from-bq-schema columns named like Card Number hold its values
+	if cfg.truncatePAN && normalizeName(c.name) == normalizeName("Card Number") {
+		return protectionMasked
+	}
+	return protectionNone
+}
+
+// buildCatalog describes the selected columns using e for example values
+func buildCatalog(cfg genCfg, e entry, selected []int) []catalogEntry {
+	catalog := make([]catalogEntry, 0, len(selected))
+	for _, i := range selected {
+		c := columns[i]
+		catalog = append(catalog, catalogEntry{
+			Name:        c.name,
+			Type:        c.kind,
+			Description: c.description,
+			Example:     typedValue{c.kind, e[i]},
+			Protection:  protection(cfg, c),
+			PII:         c.pii,
+		})
+	}
+	return catalog
+}
+
+// writeCatalog writes the catalog as json or csv depending on the file extension
+func writeCatalog(filename string, catalog []catalogEntry) error {
+	return writeFile(filename, func(w io.Writer) error {
+		if filepath.Ext(filename) == ".json" {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(catalog)
+		}
+
+		writer := csv.NewWriter(w)
+		err := writer.Write([]string{"name", "type", "description", "example", "protection", "pii"})
+		if err != nil {
+			return err
+		}
+		for _, c := range catalog {
+			err = writer.Write(c.strSlice())
+			if err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+}
@@ -0,0 +1,204 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gofakeit "github.com/brianvoe/gofakeit/v6"
+)
+
+func TestCatalogListsEmittedColumns(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"default", nil},
+		{"optional columns", []string{"-ach", "-uuid", "-split-name", "-dispute-rate", "0.1"}},
+		{"excluded columns", []string{"-exclude-fields", "CVV/CVV2,Card PIN"}},
+		{"profile", []string{"-profile", "kyc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalogFile := filepath.Join(t.TempDir(), "catalog.json")
+			header, _ := generateCSV(t, append([]string{"-count", "5", "-catalog", catalogFile}, tt.args...)...)
+			b, err := os.ReadFile(catalogFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var catalog []struct {
+				Name string `json:"name"`
+			}
+			err = json.Unmarshal(b, &catalog)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, c := range catalog {
+				names = append(names, c.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(header, ",") {
+				t.Errorf("catalog columns = %q, want the emitted columns %q", names, header)
+			}
+		})
+	}
+}
+
+func TestExcludeFields(t *testing.T) {
+	header, rows := generateCSV(t, "-count", "10", "-exclude-fields", "CVV/CVV2, Card PIN")
+	for _, name := range header {
+		if name == "CVV/CVV2" || name == "Card PIN" {
+			t.Errorf("excluded column %q is in the header %q", name, header)
+		}
+	}
+	want := -2
+	for _, c := range builtinColumns {
+		if c.option == "" {
+			want++
+		}
+	}
+	if len(header) != want {
+		t.Errorf("header has %d columns, want %d", len(header), want)
+	}
+	if len(rows) != 10 {
+		t.Errorf("got %d rows, want 10", len(rows))
+	}
+
+	_, err := selectColumns(genCfg{}, []string{"No Such Column"})
+	if err == nil {
+		t.Error("selectColumns() with an unknown excluded column succeeded")
+	}
+}
+
+func TestColumnsOrder(t *testing.T) {
+	order := writeLines(t, t.TempDir(), "order.txt", "Card Number", "Card Type Code")
+	header, _ := generateCSV(t, "-count", "5", "-columns-order", order)
+	if header[0] != "Card Number" || header[1] != "Card Type Code" {
+		t.Errorf("header = %q, want it to start with the ordered columns", header)
+	}
+
+	// a column added later only appends, the existing columns keep their positions
+	filename := filepath.Join(t.TempDir(), "data.csv")
+	cfg := parseArgs(t, "-filename", filename, "-count", "5", "-columns-order", order)
+	err := registerField(field{"Added Later", func(faker *gofakeit.Faker, row *Context) string {
+		return "x"
+	}}, typeString, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	added, _ := readCSV(t, filename)
+	want := append(append([]string(nil), header...), "Added Later")
+	if strings.Join(added, ",") != strings.Join(want, ",") {
+		t.Errorf("header with an added column = %q, want %q", added, want)
+	}
+
+	_, err = orderColumns([]int{0, 1}, []string{"No Such Column"})
+	if err == nil {
+		t.Error("orderColumns() with an unknown column succeeded")
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	tests := []struct {
+		profile string
+		want    []string
+	}{
+		{"pan-only", []string{"Card Number"}},
+		{"full-card", []string{"Card Type Code", "Card Type Full Name", "Issuing Bank", "Card Number", "Card Holder's Name",
+			"CVV/CVV2", "Issue Date", "Expiry Date", "Billing Date", "Card PIN", "Credit Limit"}},
+		{"kyc", []string{"Card Holder's Name", "Customer UUID", "First Name", "Last Name"}},
+	}
+	if len(tests) != len(profiles) {
+		t.Errorf("testing %d profiles of %d", len(tests), len(profiles))
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			header, rows := generateCSV(t, "-count", "3", "-profile", tt.profile)
+			if strings.Join(header, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("header = %q, want %q", header, tt.want)
+			}
+			for i, row := range rows {
+				for _, name := range header {
+					if row[name] == "" {
+						t.Errorf("row %d: %s is empty", i, name)
+					}
+				}
+			}
+		})
+	}
+
+	header, _ := generateCSV(t, "-count", "1", "-profile", "full-card", "-exclude-fields", "Card PIN")
+	for _, name := range header {
+		if name == "Card PIN" {
+			t.Error("exclude-fields didn't apply to the profile")
+		}
+	}
+}
+
+func TestSchemaOnly(t *testing.T) {
+	dir := t.TempDir()
+	resetState()
+	t.Cleanup(resetState)
+	flag.CommandLine = flag.NewFlagSet("schema-only", flag.ExitOnError)
+	filename := filepath.Join(dir, "data.csv")
+	files := []string{"catalog.json", "entry.proto", "schema.json", "dlp.json"}
+	err := runSchemaOnly([]string{"-filename", filename, "-ach",
+		"-catalog", filepath.Join(dir, files[0]), "-proto-schema", filepath.Join(dir, files[1]),
+		"-to-bq-schema", filepath.Join(dir, files[2]), "-dlp-info-types", filepath.Join(dir, files[3])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("schema-only wrote the data file %s", filename)
+	}
+
+	// the catalog lists the columns a generate run with the same flags writes
+	b, err := os.ReadFile(filepath.Join(dir, "catalog.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var catalog []struct {
+		Name string `json:"name"`
+	}
+	err = json.Unmarshal(b, &catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, _ := generateCSV(t, "-count", "1", "-ach")
+	if len(catalog) != len(header) {
+		t.Errorf("catalog has %d columns, generate writes %d", len(catalog), len(header))
+	}
+
+	resetState()
+	flag.CommandLine = flag.NewFlagSet("schema-only", flag.ExitOnError)
+	err = runSchemaOnly([]string{"-filename", filename})
+	if err == nil {
+		t.Error("schema-only without a schema file succeeded")
+	}
+}
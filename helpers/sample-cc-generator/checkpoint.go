@@ -0,0 +1,194 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// checkpoint records how far a run got, so it can be resumed after a crash
+type checkpoint struct {
+	// Rows is the number of entries fully written to the temp file
+	Rows int `json:"rows"`
+	// Offset is the size of the temp file after Rows entries
+	Offset int64 `json:"offset"`
+	Seed   int64 `json:"seed"`
+	// Config is a hash of the flags of the run
+	Config string `json:"config"`
+}
+
+// configHash hashes every flag value except the ones that don't change the
+// generated data, so a run is only resumed with the same configuration
+func configHash(fs *flag.FlagSet) string {
+	var values []string
+	fs.VisitAll(func(f *flag.Flag) {
+		switch f.Name {
+		case "resume", "checkpoint-every", "redact-logs", "require-faker-version", "max-regen-total":
+			return
+		}
+		values = append(values, f.Name+"="+f.Value.String())
+	})
+	sort.Strings(values)
+	h := sha256.New()
+	for _, v := range values {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func readCheckpoint(filename string) (checkpoint, error) {
+	var cp checkpoint
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return cp, err
+	}
+	err = json.Unmarshal(b, &cp)
+	return cp, err
+}
+
+func writeCheckpoint(filename string, cp checkpoint) error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
+
+// countingWriter counts the bytes written to w
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// writeResumable writes cfg.count entries to the temp file of cfg.filename,
+// recording a checkpoint every cfg.checkpointEvery entries. With cfg.resume it
+// continues from the last checkpoint, regenerating the skipped entries so the
+// result matches an uninterrupted run. The temp file and checkpoint are kept on
+// error and removed once the file is complete. It returns the number of entries
+// generated.
+func writeResumable(cfg genCfg, selected []int) (int, error) {
+	tmp := cfg.filename + ".tmp"
+	cp := checkpoint{Seed: cfg.seed, Config: cfg.configHash}
+	var f *os.File
+	var err error
+	if cfg.resume {
+		cp, err = readCheckpoint(cfg.checkpoint)
+		if err != nil {
+			return 0, fmt.Errorf("reading checkpoint: %v", err)
+		}
+		if cp.Config != cfg.configHash || cp.Seed != cfg.seed {
+			return 0, fmt.Errorf("checkpoint %s was written with different flags", cfg.checkpoint)
+		}
+		f, err = os.OpenFile(tmp, os.O_RDWR, 0755)
+		if err != nil {
+			return 0, err
+		}
+		// drop anything written after the checkpoint
+		err = f.Truncate(cp.Offset)
+		if err == nil {
+			_, err = f.Seek(cp.Offset, io.SeekStart)
+		}
+	} else {
+		f, err = os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	counter := &countingWriter{w: f, n: cp.Offset}
+	writer := newCSVWriter(counter, cfg)
+	if !cfg.resume {
+		err = writeBanner(counter, cfg)
+		if err != nil {
+			return 0, err
+		}
+		err = writer.Write(selectValues(columnNames(columns), selected))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	fakers := newFakers(cfg)
+	deduper := newCardDeduper(cfg)
+	for i := 0; i < cfg.count; i++ {
+		var tmpl rowTemplate
+		if i < len(rowTemplates) {
+			tmpl = rowTemplates[i]
+		}
+		e, err := deduper.nextEntry(fakers, cfg, tmpl)
+		if err != nil {
+			return 0, err
+		}
+		if i < cp.Rows {
+			continue
+		}
+		err = writer.Write(selectValues(e, selected))
+		if err != nil {
+			return 0, err
+		}
+		if (i+1)%cfg.checkpointEvery == 0 {
+			writer.Flush()
+			err = writer.Error()
+			if err == nil {
+				err = f.Sync()
+			}
+			if err != nil {
+				return 0, err
+			}
+			cp.Rows, cp.Offset = i+1, counter.n
+			err = writeCheckpoint(cfg.checkpoint, cp)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	writer.Flush()
+	err = writer.Error()
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		return 0, err
+	}
+	err = os.Rename(tmp, cfg.filename)
+	if err != nil {
+		return 0, err
+	}
+	// runs shorter than checkpointEvery never write a checkpoint
+	err = os.Remove(cfg.checkpoint)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	return cfg.count, nil
+}
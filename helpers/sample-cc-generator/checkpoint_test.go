@@ -0,0 +1,117 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	gofakeit "github.com/brianvoe/gofakeit/v6"
+)
+
+// runCrashing runs generate with args and a Row column counting the generated
+// entries. It panics like a crashed process while generating entry crashAt,
+// unless crashAt is negative, and reports whether it did.
+func runCrashing(t *testing.T, crashAt int, args ...string) (crashed bool) {
+	t.Helper()
+	cfg := parseArgs(t, args...)
+	calls := 0
+	err := registerField(field{"Row", func(faker *gofakeit.Faker, row *Context) string {
+		if calls == crashAt {
+			panic("crash")
+		}
+		calls++
+		return strconv.Itoa(calls)
+	}}, typeInteger, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		crashed = recover() != nil
+	}()
+	err = run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return false
+}
+
+func TestResume(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		count     int
+		crashAt   int
+		wantFiles []string
+	}{
+		{"before the first checkpoint", 120, 7, []string{"data.csv.tmp"}},
+		// the rows since the checkpoint are more than the csv writer buffers, so
+		// some were written and are dropped on resume
+		{"after a checkpoint", 120, 98, []string{"checkpoint.json", "data.csv.tmp"}},
+		{"on a checkpoint", 120, 100, []string{"checkpoint.json", "data.csv.tmp"}},
+		{"never", 5, -1, []string{"data.csv"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			count := strconv.Itoa(tt.count)
+			uninterrupted := filepath.Join(t.TempDir(), "data.csv")
+			runCrashing(t, -1, "-filename", uninterrupted, "-count", count)
+			want, err := os.ReadFile(uninterrupted)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			args := []string{"-filename", filepath.Join(dir, "data.csv"), "-count", count,
+				"-checkpoint", filepath.Join(dir, "checkpoint.json"), "-checkpoint-every", "50"}
+			if crashed := runCrashing(t, tt.crashAt, args...); crashed != (tt.crashAt >= 0) {
+				t.Fatalf("crashed = %v, want %v", crashed, tt.crashAt >= 0)
+			}
+			var files []string
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				files = append(files, e.Name())
+			}
+			if fmt.Sprint(files) != fmt.Sprint(tt.wantFiles) {
+				t.Errorf("files after the run = %q, want %q", files, tt.wantFiles)
+			}
+			if tt.crashAt < 0 {
+				return
+			}
+
+			if tt.crashAt < 50 {
+				// nothing to resume from, the run starts over
+				runCrashing(t, -1, args...)
+			} else {
+				runCrashing(t, -1, append(args, "-resume")...)
+			}
+			got, err := os.ReadFile(filepath.Join(dir, "data.csv"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("resumed file differs from an uninterrupted run:\n%s\nwant:\n%s", got, want)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "checkpoint.json")); !os.IsNotExist(err) {
+				t.Error("checkpoint kept after the run completed")
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// codebookWriter replaces the values of low-cardinality columns with integer
+// codes, assigned in order of first appearance, and records each code's value
+type codebookWriter struct {
+	writer rowWriter
+	// columns are the positions in the row of the coded columns
+	columns []int
+	names   []string
+	codes   []map[string]int
+	values  [][]string
+}
+
+// newCodebookWriter codes the columns named in cfg.codebookColumns
+func newCodebookWriter(writer rowWriter, cfg genCfg, selected []int) (*codebookWriter, error) {
+	c := &codebookWriter{writer: writer}
+	for _, name := range strings.Split(cfg.codebookColumns, ",") {
+		name = strings.TrimSpace(name)
+		pos := -1
+		for j, i := range selected {
+			if columns[i].name == name {
+				pos = j
+			}
+		}
+		if pos == -1 {
+			return nil, fmt.Errorf("codebook column %q is not in the output", name)
+		}
+		c.columns = append(c.columns, pos)
+		c.names = append(c.names, name)
+		c.codes = append(c.codes, map[string]int{})
+		c.values = append(c.values, nil)
+	}
+	return c, nil
+}
+
+func (c *codebookWriter) Write(record []string) error {
+	coded := append([]string(nil), record...)
+	for k, pos := range c.columns {
+		v := record[pos]
+		// empty values stay empty
+		if v == "" {
+			continue
+		}
+		code, ok := c.codes[k][v]
+		if !ok {
+			c.values[k] = append(c.values[k], v)
+			code = len(c.values[k])
+			c.codes[k][v] = code
+		}
+		coded[pos] = strconv.Itoa(code)
+	}
+	return c.writer.Write(coded)
+}
+
+// writeCodebook writes the column, code and value of every code as csv
+func (c *codebookWriter) writeCodebook(filename string, cfg genCfg) error {
+	return writeFile(filename, func(w io.Writer) error {
+		writer := newCSVWriter(w, cfg)
+		err := writer.Write([]string{"column", "code", "value"})
+		if err != nil {
+			return err
+		}
+		for k, name := range c.names {
+			for j, v := range c.values[k] {
+				err = writer.Write([]string{name, strconv.Itoa(j + 1), v})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestCodebook(t *testing.T) {
+	args := []string{"-count", "300", "-seed", "6", "-unknown-issuer-rate", "0.1"}
+	header, plain := generateCSV(t, args...)
+	codebook := filepath.Join(t.TempDir(), "codebook.csv")
+	codedHeader, coded := generateCSV(t, append(args, "-codebook", codebook)...)
+	if len(codedHeader) != len(header) {
+		t.Fatalf("coded header = %q, want %q", codedHeader, header)
+	}
+
+	_, lookup := readCSV(t, codebook)
+	values := map[string]map[string]string{}
+	for _, entry := range lookup {
+		if values[entry["column"]] == nil {
+			values[entry["column"]] = map[string]string{}
+		}
+		values[entry["column"]][entry["code"]] = entry["value"]
+	}
+	codedColumns := []string{"Card Type Full Name", "Issuing Bank"}
+	for _, name := range codedColumns {
+		if codes := len(values[name]); codes == 0 || codes > 20 {
+			t.Errorf("%s has %d codes, want a few", name, codes)
+		}
+	}
+
+	next := map[string]int{}
+	for i := range plain {
+		for _, name := range header {
+			got := coded[i][name]
+			if codes, ok := values[name]; ok && got != "" {
+				// codes are assigned in order of first appearance
+				code, err := strconv.Atoi(got)
+				if err != nil || code > next[name]+1 {
+					t.Fatalf("row %d: %s code %q isn't the next code %d", i, name, got, next[name]+1)
+				}
+				if code > next[name] {
+					next[name] = code
+				}
+				got = codes[got]
+			}
+			if want := plain[i][name]; got != want {
+				t.Errorf("row %d: %s decodes to %q, want %q", i, name, got, want)
+			}
+		}
+	}
+}
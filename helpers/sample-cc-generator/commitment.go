@@ -0,0 +1,121 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// saltSize is the number of random bytes in a commitment salt
+const saltSize = 32
+
+// commitment returns the salted hash of the contents of filename as
+// sha256:${hex hmac}, keyed with the salt so the commitment reveals nothing
+// about the file until the salt is published
+func commitment(filename string, salt []byte) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	mac := hmac.New(sha256.New, salt)
+	_, err = io.Copy(mac, f)
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// writeCommitment commits to the contents of filename, writing the commitment
+// and the random salt it was made with to separate files
+func writeCommitment(filename, commitFile, saltFile string) error {
+	salt := make([]byte, saltSize)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return err
+	}
+	c, err := commitment(filename, salt)
+	if err != nil {
+		return err
+	}
+	// the salt is secret until the commitment is revealed
+	err = writeFileMode(saltFile, 0600, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, hex.EncodeToString(salt))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return writeFile(commitFile, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, c)
+		return err
+	})
+}
+
+// readTrimmed returns the contents of filename without surrounding whitespace
+func readTrimmed(filename string) (string, error) {
+	b, err := os.ReadFile(filename)
+	return strings.TrimSpace(string(b)), err
+}
+
+// runVerifyCommitment checks a revealed file and salt against a commitment
+func runVerifyCommitment(args []string) error {
+	fs := flag.NewFlagSet("verify-commitment", flag.ExitOnError)
+	commitFile := fs.String("commitment", "", "Commitment file written by generate -commit")
+	saltFile := fs.String("salt", "", "Revealed salt file. Defaults to ${commitment}.salt")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s verify-commitment -commitment file [-salt file] data-file\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	// ExitOnError makes Parse exit instead of returning an error
+	_ = fs.Parse(args)
+	if *commitFile == "" || fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *saltFile == "" {
+		*saltFile = *commitFile + ".salt"
+	}
+
+	want, err := readTrimmed(*commitFile)
+	if err != nil {
+		return err
+	}
+	saltHex, err := readTrimmed(*saltFile)
+	if err != nil {
+		return err
+	}
+	salt, err := hex.DecodeString(saltHex)
+	if err != nil {
+		return fmt.Errorf("invalid salt in %s: %v", *saltFile, err)
+	}
+	got, err := commitment(fs.Arg(0), salt)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(got), []byte(want)) {
+		return fmt.Errorf("%s does not match the commitment in %s", fs.Arg(0), *commitFile)
+	}
+	fmt.Printf("%s matches the commitment in %s\n", fs.Arg(0), *commitFile)
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommitment(t *testing.T) {
+	commitFile := filepath.Join(t.TempDir(), "data.commit")
+	filename := generateFile(t, "-count", "10", "-commit", commitFile)
+
+	fi, err := os.Stat(commitFile + ".salt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Errorf("salt file permissions = %v, want -rw-------", perm)
+	}
+
+	stdout := os.Stdout
+	os.Stdout, err = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		os.Stdout.Close()
+		os.Stdout = stdout
+	}()
+	err = runVerifyCommitment([]string{"-commitment", commitFile, filename})
+	if err != nil {
+		t.Errorf("verify-commitment of the committed file: %v", err)
+	}
+
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteString("tampered\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = runVerifyCommitment([]string{"-commitment", commitFile, filename})
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("verify-commitment of a changed file: got error %v, want a mismatch", err)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// codec compresses the output file
+type codec struct {
+	// ext is appended to the output filename
+	ext       string
+	newWriter func(w io.Writer) (io.WriteCloser, error)
+}
+
+// codecs are the compressions selectable with -compress
+var codecs = map[string]codec{
+	"none": {},
+	"gzip": {".gz", func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	}},
+	"zstd": {".zst", func(w io.Writer) (io.WriteCloser, error) {
+		return zstd.NewWriter(w)
+	}},
+	"brotli": {".br", func(w io.Writer) (io.WriteCloser, error) {
+		return brotli.NewWriter(w), nil
+	}},
+}
+
+// codecNames returns the names of the codecs
+func codecNames() []string {
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// compressed calls write with a writer compressing to w with the named codec
+func compressed(w io.Writer, name string, write func(io.Writer) error) error {
+	c := codecs[name]
+	if c.newWriter == nil {
+		return write(w)
+	}
+	cw, err := c.newWriter(w)
+	if err != nil {
+		return err
+	}
+	err = write(cw)
+	if cerr := cw.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompress(t *testing.T) {
+	want, err := os.ReadFile(generateFile(t, "-count", "50", "-seed", "4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readers := map[string]func(r io.Reader) (io.ReadCloser, error){
+		"gzip": func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		"zstd": func(r io.Reader) (io.ReadCloser, error) {
+			d, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return d.IOReadCloser(), nil
+		},
+		"brotli": func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(brotli.NewReader(r)), nil
+		},
+	}
+	for _, name := range codecNames() {
+		if name == "none" {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			newReader, ok := readers[name]
+			if !ok {
+				t.Fatalf("no reader to test codec %s with", name)
+			}
+			filename := filepath.Join(t.TempDir(), "data.csv")
+			err := run(parseArgs(t, "-filename", filename, "-count", "50", "-seed", "4", "-compress", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Open(filename + codecs[name].ext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			r, err := newReader(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Error("decompressed output differs from the uncompressed output")
+			}
+			if _, err := os.Stat(filename); !os.IsNotExist(err) {
+				t.Errorf("%s written without the codec's extension", filename)
+			}
+		})
+	}
+}
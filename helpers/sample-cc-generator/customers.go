@@ -0,0 +1,78 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strconv"
+
+	gofakeit "github.com/brianvoe/gofakeit/v6"
+)
+
+// customer is a card holder shared by the cards of a num-customers run
+type customer struct {
+	name  string
+	first string
+	last  string
+	// key identifies a customer of the pool
+	key string
+	// residency and nationality are country codes, set in countries runs
+	residency   string
+	nationality string
+}
+
+// newHolder generates a card holder's name, with its first and last name
+// when generated in parts
+func newHolder(faker *gofakeit.Faker, cfg genCfg) customer {
+	if cfg.splitName && len(holderNames) == 0 {
+		first, last := faker.FirstName(), faker.LastName()
+		return customer{name: first + " " + last, first: first, last: last}
+	}
+	return customer{name: cardHolderName(faker)}
+}
+
+// holderCountries generates the residency and nationality of a card holder.
+// They match unless the holder is one of the expatRate expats.
+func holderCountries(faker *gofakeit.Faker, expatRate float64) (string, string) {
+	residency := faker.CountryAbr()
+	nationality := residency
+	if expatRate > 0 && chance(faker, expatRate) {
+		for nationality == residency {
+			nationality = faker.CountryAbr()
+		}
+	}
+	return residency, nationality
+}
+
+// newCustomers generates the cfg.numCustomers card holders of a run. They are
+// drawn from their own faker so the pool doesn't depend on the other columns,
+// and their countries from another so enabling them keeps the names.
+func newCustomers(cfg genCfg) []customer {
+	if cfg.numCustomers == 0 {
+		return nil
+	}
+	faker := gofakeit.New(columnSeed(cfg.seed, "customers"))
+	customers := make([]customer, cfg.numCustomers)
+	for i := range customers {
+		customers[i] = newHolder(faker, cfg)
+		customers[i].key = "customer-" + strconv.Itoa(i+1)
+	}
+	if cfg.countries {
+		faker = gofakeit.New(columnSeed(cfg.seed, "customer countries"))
+		for i := range customers {
+			customers[i].residency, customers[i].nationality = holderCountries(faker, cfg.expatRate)
+		}
+	}
+	return customers
+}
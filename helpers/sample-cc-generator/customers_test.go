@@ -0,0 +1,58 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestNumCustomers(t *testing.T) {
+	_, rows := generateCSV(t, "-count", "500", "-num-customers", "20", "-uuid", "-split-name", "-countries", "-expat-rate", "0.3")
+	attributes := map[string]string{}
+	for i, row := range rows {
+		id := row["Customer UUID"]
+		attrs := strings.Join([]string{row["Card Holder's Name"], row["First Name"], row["Last Name"],
+			row["Residency Country"], row["Nationality"]}, "|")
+		if want, ok := attributes[id]; ok && attrs != want {
+			t.Errorf("row %d: customer %s has attributes %q, earlier %q", i, id, attrs, want)
+		}
+		attributes[id] = attrs
+	}
+	// 500 cards among 20 customers all but surely use each of them
+	if len(attributes) != 20 {
+		t.Errorf("got %d customers, want 20", len(attributes))
+	}
+}
+
+func TestExpatRate(t *testing.T) {
+	for _, want := range []float64{0, 0.05, 0.3} {
+		_, rows := generateCSV(t, "-count", "10000", "-countries", "-expat-rate", fmt.Sprint(want))
+		expats := 0
+		for i, row := range rows {
+			if len(row["Residency Country"]) != 2 || len(row["Nationality"]) != 2 {
+				t.Fatalf("row %d: countries %q and %q aren't two letter codes", i, row["Residency Country"], row["Nationality"])
+			}
+			if row["Residency Country"] != row["Nationality"] {
+				expats++
+			}
+		}
+		if got := float64(expats) / float64(len(rows)); math.Abs(got-want) > 0.015 {
+			t.Errorf("expat-rate %v: got %v", want, got)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// dlpInfoTypes maps columns to the built-in Cloud DLP infoTypes detecting their values
+var dlpInfoTypes = map[string][]string{
+	"Card Number":        {"CREDIT_CARD_NUMBER"},
+	"Card Holder's Name": {"PERSON_NAME"},
+	"First Name":         {"FIRST_NAME"},
+	"Last Name":          {"LAST_NAME"},
+	"Routing Number":     {"US_BANK_ROUTING_MICR"},
+	"Account Number":     {"FINANCIAL_ACCOUNT_NUMBER"},
+	"Notes":              {"EMAIL_ADDRESS", "US_SOCIAL_SECURITY_NUMBER", "PHONE_NUMBER"},
+}
+
+// dlpInfoType is an infoType a DLP inspection job should look for in a column,
+// columns with several infoTypes have an entry for each. Custom infoTypes aren't built into DLP and must be defined by the job.
+type dlpInfoType struct {
+	Column   string `json:"column"`
+	InfoType string `json:"info_type"`
+	Custom   bool   `json:"custom"`
+}
+
+// buildDLPInfoTypes returns the infoTypes of the selected columns. PII columns
+// without a built-in infoType get a custom one named after the column.
+func buildDLPInfoTypes(selected []int) []dlpInfoType {
+	infoTypes := []dlpInfoType{}
+	for _, i := range selected {
+		c := columns[i]
+		if builtin, ok := dlpInfoTypes[c.name]; ok {
+			for _, infoType := range builtin {
+				infoTypes = append(infoTypes, dlpInfoType{c.name, infoType, false})
+			}
+		} else if c.pii {
+			infoTypes = append(infoTypes, dlpInfoType{c.name, customInfoType(c.name), true})
+		}
+	}
+	return infoTypes
+}
+
+// customInfoType returns name in upper snake case, e.g. CVV_CVV2 for CVV/CVV2
+func customInfoType(name string) string {
+	words := strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool {
+		return (r < 'A' || r > 'Z') && (r < '0' || r > '9')
+	})
+	return strings.Join(words, "_")
+}
+
+func writeDLPInfoTypes(filename string, infoTypes []dlpInfoType) error {
+	return writeFile(filename, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infoTypes)
+	})
+}
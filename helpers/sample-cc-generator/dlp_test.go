@@ -0,0 +1,76 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDLPInfoTypesCoverPII(t *testing.T) {
+	for name := range dlpInfoTypes {
+		if columnIndex(name) == -1 {
+			t.Errorf("infoType mapped for unknown column %q", name)
+		}
+	}
+
+	var all []int
+	for i := range columns {
+		all = append(all, i)
+	}
+	mapped := map[string]bool{}
+	for _, it := range buildDLPInfoTypes(all) {
+		mapped[it.Column] = true
+		if it.Custom != (len(dlpInfoTypes[it.Column]) == 0) {
+			t.Errorf("%s infoType %s has custom = %v", it.Column, it.InfoType, it.Custom)
+		}
+	}
+	for _, c := range columns {
+		if c.pii && !mapped[c.name] {
+			t.Errorf("pii column %q has no infoType", c.name)
+		}
+	}
+
+	filename := filepath.Join(t.TempDir(), "dlp.json")
+	generateFile(t, "-count", "1", "-ach", "-notes", "-dlp-info-types", filename)
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var infoTypes []dlpInfoType
+	err = json.Unmarshal(b, &infoTypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string][]string{}
+	for _, it := range infoTypes {
+		got[it.Column] = append(got[it.Column], it.InfoType)
+	}
+	want := map[string][]string{
+		"Card Number":        {"CREDIT_CARD_NUMBER"},
+		"Card Holder's Name": {"PERSON_NAME"},
+		"CVV/CVV2":           {"CVV_CVV2"},
+		"Card PIN":           {"CARD_PIN"},
+		"Routing Number":     {"US_BANK_ROUTING_MICR"},
+		"Account Number":     {"FINANCIAL_ACCOUNT_NUMBER"},
+		"Notes":              {"EMAIL_ADDRESS", "US_SOCIAL_SECURITY_NUMBER", "PHONE_NUMBER"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("infoTypes = %v, want %v", got, want)
+	}
+}
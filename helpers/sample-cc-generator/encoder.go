@@ -0,0 +1,189 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"sort"
+)
+
+// Encoder writes rows of column values in an output format
+type Encoder interface {
+	// WriteHeader is called once with the column names before any row
+	WriteHeader(header []string) error
+	// WriteRow writes the values of a row, in header order
+	WriteRow(values []string) error
+	// Flush is called once after the last row
+	Flush() error
+}
+
+// encoders build the Encoder of each output format, selected with -format
+var encoders = map[string]func(w io.Writer, cfg genCfg) Encoder{
+	"csv": func(w io.Writer, cfg genCfg) Encoder {
+		return &csvEncoder{w: w, cfg: cfg, writer: newCSVWriter(w, cfg)}
+	},
+	"json": func(w io.Writer, cfg genCfg) Encoder {
+		return &jsonEncoder{w: bufio.NewWriter(w)}
+	},
+	"ndjson": func(w io.Writer, cfg genCfg) Encoder {
+		return &jsonEncoder{w: bufio.NewWriter(w), lines: true}
+	},
+	"protobuf": func(w io.Writer, cfg genCfg) Encoder {
+		return &protobufEncoder{w: bufio.NewWriter(w)}
+	},
+}
+
+// RegisterEncoder adds an output format selectable with -format. Like
+// RegisterField it must be called before main runs.
+func RegisterEncoder(format string, newEncoder func(w io.Writer) Encoder) {
+	if _, ok := encoders[format]; ok {
+		log.Fatalf("format %q is already registered", format)
+	}
+	encoders[format] = func(w io.Writer, cfg genCfg) Encoder {
+		return newEncoder(w)
+	}
+}
+
+// encoderFormats returns the registered formats
+func encoderFormats() []string {
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// encoderWriter adapts an Encoder to a rowWriter
+type encoderWriter struct {
+	enc    Encoder
+	header bool
+}
+
+func (e *encoderWriter) Write(record []string) error {
+	if !e.header {
+		e.header = true
+		return e.enc.WriteHeader(record)
+	}
+	return e.enc.WriteRow(record)
+}
+
+// writeEncoded writes the header and cfg.count entries to w in cfg.format,
+// returning the number of entries generated
+func writeEncoded(w io.Writer, cfg genCfg, selected []int) (int, error) {
+	enc := encoders[cfg.format](w, cfg)
+	written, err := writeEntries(&encoderWriter{enc: enc}, cfg, selected)
+	if err != nil {
+		return written, err
+	}
+	return written, enc.Flush()
+}
+
+// csvEncoder writes the optional banner, the header and rows as csv
+type csvEncoder struct {
+	w      io.Writer
+	cfg    genCfg
+	writer *csv.Writer
+}
+
+func (c *csvEncoder) WriteHeader(header []string) error {
+	err := writeBanner(c.w, c.cfg)
+	if err != nil {
+		return err
+	}
+	return c.writer.Write(header)
+}
+
+func (c *csvEncoder) WriteRow(values []string) error {
+	return c.writer.Write(values)
+}
+
+func (c *csvEncoder) Flush() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// jsonEncoder writes rows as json objects keyed by column name, either in an
+// array or one per line. Numeric and boolean columns are written unquoted.
+type jsonEncoder struct {
+	w     *bufio.Writer
+	lines bool
+	keys  [][]byte
+	kinds []string
+	rows  int
+}
+
+func (j *jsonEncoder) WriteHeader(header []string) error {
+	for _, name := range header {
+		key, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		j.keys = append(j.keys, key)
+		kind := typeString
+		if i := columnIndex(name); i >= 0 {
+			kind = columns[i].kind
+		}
+		j.kinds = append(j.kinds, kind)
+	}
+	if !j.lines {
+		_, err := j.w.WriteString("[")
+		return err
+	}
+	return nil
+}
+
+func (j *jsonEncoder) WriteRow(values []string) error {
+	if !j.lines {
+		sep := ",\n  "
+		if j.rows == 0 {
+			sep = "\n  "
+		}
+		j.w.WriteString(sep)
+	}
+	j.rows++
+	j.w.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			j.w.WriteByte(',')
+		}
+		j.w.Write(j.keys[i])
+		j.w.WriteByte(':')
+		b, err := typedValue{j.kinds[i], v}.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		j.w.Write(b)
+	}
+	_, err := j.w.WriteString("}")
+	if err == nil && j.lines {
+		err = j.w.WriteByte('\n')
+	}
+	return err
+}
+
+func (j *jsonEncoder) Flush() error {
+	if !j.lines {
+		if j.rows > 0 {
+			j.w.WriteString("\n")
+		}
+		j.w.WriteString("]\n")
+	}
+	return j.w.Flush()
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTypedCreditLimit(t *testing.T) {
+	_, rows := generateCSV(t, "-count", "20", "-seed", "3")
+	filename := generateFile(t, "-count", "20", "-seed", "3", "-format", "json")
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`"Credit Limit":-?[0-9]+[,}]`).Match(b) {
+		t.Errorf("json credit limits aren't numbers:\n%s", b)
+	}
+	var entries []map[string]interface{}
+	err = json.Unmarshal(b, &entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(rows) {
+		t.Fatalf("got %d json entries, want %d", len(entries), len(rows))
+	}
+	for i, e := range entries {
+		limit, ok := e["Credit Limit"].(float64)
+		if !ok {
+			t.Fatalf("entry %d: credit limit %#v isn't a number", i, e["Credit Limit"])
+		}
+		if _, ok := e["Card Number"].(string); !ok {
+			t.Errorf("entry %d: card number %#v isn't a string", i, e["Card Number"])
+		}
+		if csvLimit := rows[i]["Credit Limit"]; strconv.Itoa(int(limit)) != csvLimit {
+			t.Errorf("entry %d: json credit limit %v, csv %q", i, limit, csvLimit)
+		}
+		if _, err := strconv.Atoi(rows[i]["Credit Limit"]); err != nil {
+			t.Errorf("row %d: csv credit limit isn't digits: %v", i, err)
+		}
+	}
+}
+
+// countingEncoder counts the calls it gets
+type countingEncoder struct {
+	headers, rows, flushes int
+	width                  int
+}
+
+func (c *countingEncoder) WriteHeader(header []string) error {
+	c.headers++
+	c.width = len(header)
+	return nil
+}
+
+func (c *countingEncoder) WriteRow(values []string) error {
+	c.rows++
+	return nil
+}
+
+func (c *countingEncoder) Flush() error {
+	c.flushes++
+	return nil
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	enc := &countingEncoder{}
+	RegisterEncoder("counting", func(w io.Writer) Encoder { return enc })
+	t.Cleanup(func() { delete(encoders, "counting") })
+
+	header, _ := generateCSV(t, "-count", "1")
+	generateFile(t, "-count", "25", "-format", "counting")
+	if enc.headers != 1 || enc.rows != 25 || enc.flushes != 1 {
+		t.Errorf("encoder got %d headers, %d rows and %d flushes, want 1, 25 and 1", enc.headers, enc.rows, enc.flushes)
+	}
+	if enc.width != len(header) {
+		t.Errorf("encoder header has %d columns, want %d", enc.width, len(header))
+	}
+}
+
+func TestCSVOnlyFlags(t *testing.T) {
+	for _, flags := range []string{"-banner", "-batch-marker", "-checkpoint cp.json", "-partition-by Card_Type_Code"} {
+		for _, format := range []string{"json", "ndjson", "protobuf"} {
+			args := "-count 1 -format " + format + " " + flags
+			out, err := runMain(t, args)
+			if err == nil || !strings.Contains(out, "require the csv format") {
+				t.Errorf("%s: got error %v, want the csv format required:\n%s", args, err, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+const (
+	encodingUTF8   = "utf-8"
+	encodingLatin1 = "latin1"
+	// unmappableReplace writes runes latin1 can't represent as '?', unmappableError fails the run
+	unmappableReplace = "replace"
+	unmappableError   = "error"
+)
+
+// latin1Writer transcodes the utf-8 written to it to ISO-8859-1
+type latin1Writer struct {
+	w            io.Writer
+	onUnmappable string
+	// pending holds an incomplete rune split between writes
+	pending []byte
+	buf     []byte
+}
+
+func (l *latin1Writer) Write(p []byte) (int, error) {
+	b := append(l.pending, p...)
+	l.buf = l.buf[:0]
+	for len(b) > 0 {
+		if !utf8.FullRune(b) {
+			break
+		}
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size == 1 || r > 0xff {
+			if l.onUnmappable == unmappableError {
+				return 0, fmt.Errorf("%q can't be encoded in latin1", string(b[:size]))
+			}
+			r = '?'
+		}
+		l.buf = append(l.buf, byte(r))
+		b = b[size:]
+	}
+	l.pending = append(l.pending[:0], b...)
+	_, err := l.w.Write(l.buf)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// encoded calls write with a writer transcoding to w in the named encoding
+func encoded(w io.Writer, encoding, onUnmappable string, write func(io.Writer) error) error {
+	if encoding != encodingLatin1 {
+		return write(w)
+	}
+	l := &latin1Writer{w: w, onUnmappable: onUnmappable}
+	err := write(l)
+	if err == nil && len(l.pending) > 0 {
+		err = fmt.Errorf("output ends with an incomplete utf-8 sequence %q", l.pending)
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// decodeLatin1 returns the utf-8 text of latin1 bytes
+func decodeLatin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+func TestLatin1(t *testing.T) {
+	dir := t.TempDir()
+	names := writeLines(t, dir, "names.txt", "José Müller", "Zoë Ångström", "François Ødegård")
+	args := []string{"-count", "50", "-names-file", names}
+	want, err := os.ReadFile(generateFile(t, args...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(generateFile(t, append(args, "-output-encoding", "latin1")...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(b, want) {
+		t.Fatal("latin1 output is the same as utf-8")
+	}
+	if got := decodeLatin1(b); got != string(want) {
+		t.Errorf("latin1 output decodes to\n%s\nwant\n%s", got, want)
+	}
+
+	var buf bytes.Buffer
+	l := &latin1Writer{w: &buf, onUnmappable: unmappableReplace}
+	// runes split between writes are transcoded whole
+	for _, c := range []byte("Zoë 李 €") {
+		_, err = l.Write([]byte{c})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := decodeLatin1(buf.Bytes()); got != "Zoë ? ?" {
+		t.Errorf("replaced unmappable runes = %q, want %q", got, "Zoë ? ?")
+	}
+
+	l = &latin1Writer{w: &buf, onUnmappable: unmappableError}
+	_, err = l.Write([]byte("Zoë 李"))
+	if err == nil || !strings.Contains(err.Error(), "can't be encoded in latin1") {
+		t.Errorf("got error %v, want an unmappable rune", err)
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	gofakeit "github.com/brianvoe/gofakeit/v6"
+)
+
+// FieldGenerator generates the value of a single column
+type FieldGenerator interface {
+	// Name is the column header, it must match a registered column
+	Name() string
+	// Generate returns the column value for the current row
+	Generate(faker *gofakeit.Faker, row *Context) string
+}
+
+// Context holds the state of the row being generated
+type Context struct {
+	cfg    genCfg
+	values map[string]string
+	card   *gofakeit.CreditCardInfo
+	issued time.Time
+	// first and last are the generated parts of the card holder's name
+	first string
+	last  string
+	// customers is the pool card holders are drawn from, if any, and
+	// customerKey identifies the card holder within it
+	customers   []customer
+	customerKey string
+	// parent is the account the card belongs to, if any
+	parent *parentAccount
+	// notesPII is set when PII was embedded in the notes
+	notesPII bool
+	// residency and nationality are the card holder's countries
+	residency   string
+	nationality string
+	// constraints is the faker card number constraints draw from, a stream of
+	// its own so they don't shift the other columns
+	constraints *gofakeit.Faker
+}
+
+// Value returns the value of a column generated earlier in the row
+func (c *Context) Value(name string) string {
+	return c.values[name]
+}
+
+// field adapts a function to a FieldGenerator
+type field struct {
+	name     string
+	generate func(faker *gofakeit.Faker, row *Context) string
+}
+
+func (f field) Name() string {
+	return f.name
+}
+
+func (f field) Generate(faker *gofakeit.Faker, row *Context) string {
+	return f.generate(faker, row)
+}
+
+// generators run in order for each row, so later fields can read earlier ones.
+// The order is kept stable so the same seed keeps generating the same data.
+var generators = []FieldGenerator{
+	field{"Issue Date", func(faker *gofakeit.Faker, row *Context) string {
+		minIssueT := time.Date(minIssueYear, time.January, 1, 0, 0, 0, 0, time.UTC)
+		maxIssueT := time.Date(maxIssueYear, time.January, 1, 0, 0, 0, 0, time.UTC)
+		// issued between min/max issue time
+		row.issued = faker.DateRange(minIssueT, maxIssueT)
+		return row.issued.Format("01/2006")
+	}},
+	field{"Card Holder's Name", func(faker *gofakeit.Faker, row *Context) string {
+		var holder customer
+		if len(row.customers) > 0 {
+			holder = row.customers[faker.Number(0, len(row.customers)-1)]
+		} else {
+			holder = newHolder(faker, row.cfg)
+		}
+		row.first, row.last, row.customerKey = holder.first, holder.last, holder.key
+		row.residency, row.nationality = holder.residency, holder.nationality
+		return holder.name
+	}},
+	field{"Card Number", func(faker *gofakeit.Faker, row *Context) string {
+		if row.cfg.testBins != "" {
+			row.card = gatewayCard(faker, row.cfg.testBins)
+		} else {
+			row.card = faker.CreditCard()
+		}
+		if row.cfg.normalizeLength > 0 {
+			row.card.Number = normalizeCardNumber(row.constraints, row.card.Number, row.cfg.normalizeLength)
+		}
+		if row.cfg.truncatePAN {
+			return truncatePAN(row.card.Number)
+		}
+		return row.card.Number
+	}},
+	field{"Last Four", func(faker *gofakeit.Faker, row *Context) string {
+		// truncated card numbers keep the last four, and overridden ones are matched too
+		number := row.Value("Card Number")
+		if len(number) < 4 {
+			return number
+		}
+		return number[len(number)-4:]
+	}},
+	field{"CVV/CVV2", func(faker *gofakeit.Faker, row *Context) string {
+		// faker picks the cvv size of a random network, regenerate to match this one
+		if n := cvvLength(row.card.Type); len(row.card.Cvv) != n {
+			return faker.DigitN(uint(n))
+		}
+		return row.card.Cvv
+	}},
+	field{"Card Type Full Name", func(faker *gofakeit.Faker, row *Context) string {
+		return row.card.Type
+	}},
+	field{"Card Type Code", func(faker *gofakeit.Faker, row *Context) string {
+		return ccShortCode(row.card.Type)
+	}},
+	field{"Expiry Date", func(faker *gofakeit.Faker, row *Context) string {
+		// expiry is 3-5 years after issue
+		expiryTime := faker.DateRange(row.issued.AddDate(3, 0, 0), row.issued.AddDate(5, 0, 0))
+		if row.cfg.expiryClustering > 0 && chance(faker, row.cfg.expiryClustering) {
+			expiryTime = clusterExpiry(expiryTime, row.issued, row.cfg.expiryMonths)
+		}
+		return expiryTime.Format("01/2006")
+	}},
+	field{"Issuing Bank", func(faker *gofakeit.Faker, row *Context) string {
+		bank := issueBank(faker, row.card.Type)
+		if row.parent != nil {
+			if row.parent.bank == "" {
+				row.parent.bank = bank
+			}
+			bank = row.parent.bank
+		}
+		if row.cfg.unknownIssuerRate > 0 && chance(faker, row.cfg.unknownIssuerRate) {
+			return ""
+		}
+		return bank
+	}},
+	field{"Billing Date", func(faker *gofakeit.Faker, row *Context) string {
+		return strconv.Itoa(faker.Number(1, 27))
+	}},
+	field{"Card PIN", func(faker *gofakeit.Faker, row *Context) string {
+		// 4 digit num
+		return strconv.Itoa(faker.Number(1000, 9999))
+	}},
+	field{"Credit Limit", func(faker *gofakeit.Faker, row *Context) string {
+		limit := faker.Number(minCreditLimit, maxCreditLimit)
+		if row.parent != nil {
+			if row.parent.limit == 0 {
+				row.parent.limit = limit * row.cfg.accountsHierarchy
+			}
+			limit = row.parent.limit / row.cfg.accountsHierarchy
+		}
+		if row.cfg.allowNegativeLimit && chance(faker, row.cfg.negativeLimitRate) {
+			limit = -limit
+		}
+		return strconv.Itoa(limit)
+	}},
+	field{"Routing Number", func(faker *gofakeit.Faker, row *Context) string {
+		return routingNumber(faker)
+	}},
+	field{"Account Number", func(faker *gofakeit.Faker, row *Context) string {
+		return faker.AchAccount()
+	}},
+	field{"Customer UUID", func(faker *gofakeit.Faker, row *Context) string {
+		// every card has its own card holder unless drawn from the customers pool
+		key := row.customerKey
+		if key == "" {
+			key = row.card.Number
+		}
+		return uuidV5(row.cfg.uuidNamespace, customerIdentity(row.Value("Card Holder's Name"), key))
+	}},
+	field{"First Name", func(faker *gofakeit.Faker, row *Context) string {
+		first, _ := splitName(row)
+		return first
+	}},
+	field{"Last Name", func(faker *gofakeit.Faker, row *Context) string {
+		_, last := splitName(row)
+		return last
+	}},
+	field{"Due Date", func(faker *gofakeit.Faker, row *Context) string {
+		day, err := strconv.Atoi(row.Value("Billing Date"))
+		if err != nil {
+			return ""
+		}
+		return dueDate(row.issued, day, row.cfg.graceDays).Format("01/02/2006")
+	}},
+	field{"Disputed", func(faker *gofakeit.Faker, row *Context) string {
+		return strconv.FormatBool(chance(faker, row.cfg.disputeRate))
+	}},
+	field{"Balance", func(faker *gofakeit.Faker, row *Context) string {
+		limit, err := strconv.Atoi(row.Value("Credit Limit"))
+		if err != nil {
+			return ""
+		}
+		return strconv.Itoa(accountBalance(faker, limit, row.cfg.overLimitRate))
+	}},
+	field{"Is Duplicate", func(faker *gofakeit.Faker, row *Context) string {
+		// copies are flagged when they are written
+		return "false"
+	}},
+	field{"Parent Account ID", func(faker *gofakeit.Faker, row *Context) string {
+		return row.parent.id
+	}},
+	field{"Parent Credit Limit", func(faker *gofakeit.Faker, row *Context) string {
+		return strconv.Itoa(row.parent.limit)
+	}},
+	field{"Residency Country", func(faker *gofakeit.Faker, row *Context) string {
+		// customers of a num-customers pool keep their countries
+		if row.residency == "" {
+			row.residency, row.nationality = holderCountries(faker, row.cfg.expatRate)
+		}
+		return row.residency
+	}},
+	field{"Nationality", func(faker *gofakeit.Faker, row *Context) string {
+		return row.nationality
+	}},
+	field{"Notes", func(faker *gofakeit.Faker, row *Context) string {
+		note := faker.Sentence(10)
+		if row.cfg.embedPIIRate > 0 && chance(faker, row.cfg.embedPIIRate) {
+			row.notesPII = true
+			words := strings.Fields(note)
+			i := faker.Number(0, len(words))
+			words = append(words[:i], append([]string{notePII(faker)}, words[i:]...)...)
+			note = strings.Join(words, " ")
+		}
+		return note
+	}},
+	field{"Notes PII", func(faker *gofakeit.Faker, row *Context) string {
+		return strconv.FormatBool(row.notesPII)
+	}},
+}
+
+// splitName returns the first and last name of the card holder. Names that
+// weren't generated in parts, e.g. from a names file, are split at the last space.
+func splitName(row *Context) (string, string) {
+	name := row.Value("Card Holder's Name")
+	if row.first != "" && name == row.first+" "+row.last {
+		return row.first, row.last
+	}
+	i := strings.LastIndex(name, " ")
+	if i == -1 {
+		return name, ""
+	}
+	return name[:i], name[i+1:]
+}
+
+// RegisterField adds a custom column generated by g after the built-in columns.
+// It must be called before main runs, e.g. from an init function in a build
+// tagged file.
+func RegisterField(g FieldGenerator, kind, description string, pii bool) {
+	err := registerField(g, kind, description, pii)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func registerField(g FieldGenerator, kind, description string, pii bool) error {
+	if columnIndex(g.Name()) != -1 {
+		return fmt.Errorf("column %q is already registered", g.Name())
+	}
+	columns = append(columns, column{g.Name(), kind, description, pii, ""})
+	generators = append(generators, g)
+	return nil
+}
@@ -0,0 +1,294 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	gofakeit "github.com/brianvoe/gofakeit/v6"
+)
+
+// rate returns the fraction of rows for which match is true
+func rate(rows []map[string]string, match func(row map[string]string) bool) float64 {
+	n := 0
+	for _, row := range rows {
+		if match(row) {
+			n++
+		}
+	}
+	return float64(n) / float64(len(rows))
+}
+
+func TestDisputeRate(t *testing.T) {
+	for _, want := range []float64{0.015, 0.2, 1} {
+		_, rows := generateCSV(t, "-count", "20000", "-dispute-rate", fmt.Sprint(want))
+		got := rate(rows, func(row map[string]string) bool { return row["Disputed"] == "true" })
+		if math.Abs(got-want) > 0.01 {
+			t.Errorf("dispute rate %v: got %v", want, got)
+		}
+	}
+	header, _ := generateCSV(t, "-count", "1")
+	for _, name := range header {
+		if name == "Disputed" {
+			t.Error("Disputed column written without dispute-rate")
+		}
+	}
+}
+
+func TestCVVLength(t *testing.T) {
+	_, rows := generateCSV(t, "-count", "2000")
+	seen := map[string]bool{}
+	for i, row := range rows {
+		network, cvv := row["Card Type Full Name"], row["CVV/CVV2"]
+		want := 3
+		if network == "American Express" {
+			want = 4
+		}
+		if len(cvv) != want || strings.Trim(cvv, "0123456789") != "" {
+			t.Errorf("row %d: %s cvv %q, want %d digits", i, network, cvv, want)
+		}
+		seen[network] = true
+	}
+	for _, network := range []string{"American Express", "Visa", "Mastercard"} {
+		if !seen[network] {
+			t.Errorf("no %s cards generated", network)
+		}
+	}
+}
+
+func TestRegisterField(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.csv")
+	cfg := parseArgs(t, "-count", "20", "-filename", filename)
+	RegisterField(field{"Card Label", func(faker *gofakeit.Faker, row *Context) string {
+		return "label-" + row.Value("Card Type Code")
+	}}, typeString, "Label built from an earlier column", false)
+	err := run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, rows := readCSV(t, filename)
+	if header[len(header)-1] != "Card Label" {
+		t.Fatalf("header = %q, want Card Label last", header)
+	}
+	for i, row := range rows {
+		if want := "label-" + row["Card Type Code"]; row["Card Label"] != want {
+			t.Errorf("row %d: Card Label = %q, want %q", i, row["Card Label"], want)
+		}
+	}
+}
+
+func TestNormalizeLength(t *testing.T) {
+	for _, length := range []int{12, 16, 19} {
+		t.Run(fmt.Sprint(length), func(t *testing.T) {
+			_, rows := generateCSV(t, "-count", "500", "-normalize-length", fmt.Sprint(length))
+			for i, row := range rows {
+				number := row["Card Number"]
+				if len(number) != length || !luhnValid(number) {
+					t.Errorf("row %d: card number %q isn't a Luhn valid %d digit number", i, number, length)
+				}
+			}
+		})
+	}
+}
+
+func TestTruncatePAN(t *testing.T) {
+	_, full := generateCSV(t, "-count", "200")
+	filename := generateFile(t, "-count", "200", "-truncate-pan")
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, rows := readCSV(t, filename)
+	for i, row := range rows {
+		number := row["Card Number"]
+		parts := strings.Split(number, "...")
+		if len(parts) != 2 || len(parts[0]) != 6 || len(parts[1]) != 4 || strings.Trim(parts[0]+parts[1], "0123456789") != "" {
+			t.Errorf("row %d: card number %q isn't 6 digits...4 digits", i, number)
+		}
+		pan := full[i]["Card Number"]
+		if number != pan[:6]+"..."+pan[len(pan)-4:] {
+			t.Errorf("row %d: card number %q doesn't truncate %q", i, number, pan)
+		}
+		if strings.Contains(string(b), pan) {
+			t.Errorf("row %d: full card number %q is in the output", i, pan)
+		}
+	}
+}
+
+func TestSplitName(t *testing.T) {
+	_, rows := generateCSV(t, "-count", "200", "-split-name")
+	for i, row := range rows {
+		first, last := row["First Name"], row["Last Name"]
+		if first == "" || last == "" {
+			t.Errorf("row %d: first name %q and last name %q can't be empty", i, first, last)
+		}
+		if name := row["Card Holder's Name"]; first+" "+last != name {
+			t.Errorf("row %d: %q + %q doesn't make up the name %q", i, first, last, name)
+		}
+	}
+
+	header, _ := generateCSV(t, "-count", "1", "-split-name", "-full-name=false")
+	for _, name := range header {
+		if name == "Card Holder's Name" {
+			t.Errorf("header %q has the combined name with full-name=false", header)
+		}
+	}
+}
+
+func TestUnknownIssuerRate(t *testing.T) {
+	for _, want := range []float64{0, 0.1, 0.5} {
+		_, rows := generateCSV(t, "-count", "10000", "-unknown-issuer-rate", fmt.Sprint(want))
+		got := rate(rows, func(row map[string]string) bool { return row["Issuing Bank"] == "" })
+		if math.Abs(got-want) > 0.015 {
+			t.Errorf("unknown issuer rate %v: got %v", want, got)
+		}
+		for i, row := range rows {
+			if !luhnValid(row["Card Number"]) {
+				t.Fatalf("row %d: card number %s isn't valid", i, row["Card Number"])
+			}
+		}
+	}
+}
+
+func TestBalance(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		overLimit    float64
+		negativeRate float64
+	}{
+		{"within limit", nil, 0, 0},
+		{"over limit", []string{"-over-limit-rate", "0.2"}, 0.2, 0},
+		{"negative limit", []string{"-allow-negative-limit", "-negative-limit-rate", "0.1"}, 0, 0.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rows := generateCSV(t, append([]string{"-count", "10000", "-balance"}, tt.args...)...)
+			over, negative := 0, 0
+			for i, row := range rows {
+				limit, err := strconv.Atoi(row["Credit Limit"])
+				if err != nil {
+					t.Fatal(err)
+				}
+				balance, err := strconv.Atoi(row["Balance"])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if limit < 0 {
+					negative++
+					limit = -limit
+				} else if balance > limit {
+					over++
+				}
+				if limit < minCreditLimit || limit > maxCreditLimit || balance < 0 || balance > limit+limit/2+1 {
+					t.Errorf("row %d: balance %d of limit %s is out of range", i, balance, row["Credit Limit"])
+				}
+			}
+			if got := float64(over) / float64(len(rows)); math.Abs(got-tt.overLimit) > 0.015 {
+				t.Errorf("over-limit rate = %v, want %v", got, tt.overLimit)
+			}
+			if got := float64(negative) / float64(len(rows)); math.Abs(got-tt.negativeRate) > 0.015 {
+				t.Errorf("negative limit rate = %v, want %v", got, tt.negativeRate)
+			}
+		})
+	}
+}
+
+// topMonthsShare returns the fraction of expiry dates in their n most common months of the year
+func topMonthsShare(rows []map[string]string, n int) float64 {
+	counts := make([]int, 12)
+	for _, row := range rows {
+		month, _ := strconv.Atoi(row["Expiry Date"][:2])
+		counts[month-1]++
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(counts)))
+	top := 0
+	for _, c := range counts[:n] {
+		top += c
+	}
+	return float64(top) / float64(len(rows))
+}
+
+func TestExpiryClustering(t *testing.T) {
+	uniform := float64(expiryClusters) / 12
+	_, rows := generateCSV(t, "-count", "10000")
+	if got := topMonthsShare(rows, expiryClusters); got > uniform+0.03 {
+		t.Errorf("without clustering the top %d months hold %v of expiries, want about %v", expiryClusters, got, uniform)
+	}
+	_, rows = generateCSV(t, "-count", "10000", "-expiry-clustering", "0.6")
+	// clustered expiries are all in the top months, the others are uniform
+	want := 0.6 + 0.4*uniform
+	if got := topMonthsShare(rows, expiryClusters); math.Abs(got-want) > 0.03 {
+		t.Errorf("with clustering the top %d months hold %v of expiries, want about %v", expiryClusters, got, want)
+	}
+}
+
+func TestEmbedPIIRate(t *testing.T) {
+	pii := regexp.MustCompile(`[\w.+-]+@[\w-]+\.[\w.]+|\b\d{3}-\d{2}-\d{4}\b|\(?\b\d{3}\)?[-. ]?\d{3}[-.]\d{4}\b`)
+	for _, want := range []float64{0, 0.05, 0.3} {
+		_, rows := generateCSV(t, "-count", "5000", "-notes", "-embed-pii-rate", fmt.Sprint(want))
+		got := rate(rows, func(row map[string]string) bool { return row["Notes PII"] == "true" })
+		if math.Abs(got-want) > 0.02 {
+			t.Errorf("embed-pii-rate %v: got %v", want, got)
+		}
+		for i, row := range rows {
+			if found := pii.MatchString(row["Notes"]); found != (row["Notes PII"] == "true") {
+				t.Errorf("row %d: notes %q are flagged %s but the regex found PII %v", i, row["Notes"], row["Notes PII"], found)
+			}
+		}
+	}
+}
+
+func TestLastFour(t *testing.T) {
+	for _, args := range [][]string{nil, {"-truncate-pan"}, {"-normalize-length", "19"}, {"-test-bins", "adyen"}} {
+		t.Run(fmt.Sprint(args), func(t *testing.T) {
+			_, rows := generateCSV(t, append([]string{"-count", "200", "-emit-last-four"}, args...)...)
+			for i, row := range rows {
+				number, lastFour := row["Card Number"], row["Last Four"]
+				if len(lastFour) != 4 || !strings.HasSuffix(number, lastFour) {
+					t.Errorf("row %d: last four %q don't end card number %q", i, lastFour, number)
+				}
+			}
+		})
+	}
+}
+
+func TestExpiryAfterIssue(t *testing.T) {
+	for _, args := range [][]string{nil, {"-expiry-clustering", "1"}} {
+		_, rows := generateCSV(t, append([]string{"-count", "20000"}, args...)...)
+		for i, row := range rows {
+			issued, err := time.Parse("01/2006", row["Issue Date"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			expiry, err := time.Parse("01/2006", row["Expiry Date"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expiry.Before(issued.AddDate(3, 0, 0)) || expiry.After(issued.AddDate(5, 0, 0)) {
+				t.Errorf("row %d %v: expiry %s isn't 3-5 years after issue %s", i, args, row["Expiry Date"], row["Issue Date"])
+			}
+		}
+	}
+}
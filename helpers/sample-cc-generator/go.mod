@@ -2,4 +2,8 @@ module github.com/GoogleCloudPlatform/terraform-google-secured-data-warehouse/he
 
 go 1.16
 
-require github.com/brianvoe/gofakeit/v6 v6.9.0 // indirect
+require (
+	github.com/andybalholm/brotli v1.0.4
+	github.com/brianvoe/gofakeit/v6 v6.9.0
+	github.com/klauspost/compress v1.13.6
+)
@@ -0,0 +1,41 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "fmt"
+
+// parentAccount is a corporate account grouping the child cards of an
+// accounts-hierarchy run. Children share its issuing bank and split its
+// credit limit evenly.
+type parentAccount struct {
+	id    string
+	bank  string
+	limit int
+	// children is the number of entries started under the account
+	children int
+}
+
+// nextChild starts a child card of the current parent account, moving to a new
+// parent after every cfg.accountsHierarchy children
+func (f *fakers) nextChild(cfg genCfg) {
+	if cfg.accountsHierarchy == 0 {
+		return
+	}
+	if f.parent == nil || f.parent.children == cfg.accountsHierarchy {
+		f.parents++
+		f.parent = &parentAccount{id: fmt.Sprintf("P%08d", f.parents)}
+	}
+	f.parent.children++
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+func TestAccountsHierarchy(t *testing.T) {
+	_, rows := generateCSV(t, "-count", "22", "-accounts-hierarchy", "4")
+	type parent struct {
+		children int
+		bank     string
+		limit    string
+	}
+	parents := map[string]*parent{}
+	var order []string
+	for i, row := range rows {
+		id := row["Parent Account ID"]
+		p, ok := parents[id]
+		if !ok {
+			p = &parent{bank: row["Issuing Bank"], limit: row["Parent Credit Limit"]}
+			parents[id] = p
+			order = append(order, id)
+		}
+		p.children++
+		if row["Issuing Bank"] != p.bank || row["Parent Credit Limit"] != p.limit {
+			t.Errorf("row %d: bank %q and parent limit %s differ from parent %s's %q and %s",
+				i, row["Issuing Bank"], row["Parent Credit Limit"], id, p.bank, p.limit)
+		}
+		limit, err := strconv.Atoi(row["Credit Limit"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parentLimit, _ := strconv.Atoi(p.limit); limit != parentLimit/4 {
+			t.Errorf("row %d: credit limit %d isn't a quarter of the parent limit %d", i, limit, parentLimit)
+		}
+	}
+
+	// the last parent has the 2 remaining children
+	if len(order) != 6 {
+		t.Fatalf("got %d parents, want 6", len(order))
+	}
+	for i, id := range order {
+		if want := fmt.Sprintf("P%08d", i+1); id != want {
+			t.Errorf("parent %d id = %s, want %s", i, id, want)
+		}
+		want := 4
+		if i == len(order)-1 {
+			want = 2
+		}
+		if parents[id].children != want {
+			t.Errorf("parent %s has %d children, want %d", id, parents[id].children, want)
+		}
+	}
+}
@@ -18,76 +18,218 @@ import (
 	"encoding/csv"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"math/rand"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	gofakeit "github.com/brianvoe/gofakeit/v6"
 )
 
 const (
-	minIssueYear   = "2000"
-	maxIssueYear   = "2021"
+	minIssueYear   = 2000
+	maxIssueYear   = 2021
 	minCreditLimit = 999
 	maxCreditLimit = 999999
 )
 
 var (
-	issueBanks = []string{"Chase", "Wells Fargo", "Bank of America", "Capital One", "Barclays", "GE Capital", "U.S. Bancorp"}
-	csvHeaders = []string{
-		"Card Type Code",
-		"Card Type Full Name",
-		"Issuing Bank",
-		"Card Number",
-		"Card Holder's Name",
-		"CVV/CVV2",
-		"Issue Date",
-		"Expiry Date",
-		"Billing Date",
-		"Card PIN",
-		"Credit Limit",
-	}
+	// holderNames overrides faker generated card holder names when set
+	holderNames []string
+	// columnsOrder pins the order of the named output columns
+	columnsOrder []string
+	// rowTemplates holds partial rows used verbatim, one per generated entry
+	rowTemplates []rowTemplate
+	issueBanks   = []string{"Chase", "Wells Fargo", "Bank of America", "Capital One", "Barclays", "GE Capital", "U.S. Bancorp"}
 )
 
 // generator config
 type genCfg struct {
-	seed     int64
-	count    int
-	filename string
-}
-
-// csv entry
-type entry struct {
-	cardTypeCode     string
-	cardTypeFullName string
-	issuingBank      string
-	cardNumber       string
-	cardHolderName   string
-	cvv              string
-	issueDate        string
-	expiryDate       string
-	billingDate      string
-	cardPin          string
-	limit            string
-}
-
-func (e entry) strSlice() []string {
-	return []string{
-		e.cardTypeCode,
-		e.cardTypeFullName,
-		e.issuingBank,
-		e.cardNumber,
-		e.cardHolderName,
-		e.cvv,
-		e.issueDate,
-		e.expiryDate,
-		e.billingDate,
-		e.cardPin,
-		e.limit,
+	seed      int64
+	count     int
+	filename  string
+	outputDir string
+	namesFile string
+	banksFile string
+	catalog   string
+	// stats is the json file to write per column statistics to
+	stats string
+	// commit is the file to write a salted hash commitment of the output to,
+	// the salt is written to commitSalt
+	commit     string
+	commitSalt string
+	// dlpInfoTypes is the json file to write the DLP infoType of each column to
+	dlpInfoTypes string
+	// profile names the curated column set to write, empty for all columns
+	profile string
+	// exclude is a comma separated list of columns to leave out
+	exclude string
+	ach     bool
+	uuid    bool
+	// splitName adds first and last name columns, fullName keeps the combined name
+	splitName bool
+	fullName  bool
+	// uuidNamespace is the namespace customer uuids are derived in
+	uuidNamespace [16]byte
+	// graceDays is the number of days between billing and due dates
+	graceDays int
+	// unknownIssuerRate is the fraction of entries with an empty issuing bank
+	unknownIssuerRate float64
+	// disputeRate is the fraction of entries flagged as disputed
+	disputeRate float64
+	// balance adds a balance column, overLimitRate of which exceed the credit limit
+	balance       bool
+	overLimitRate float64
+	// negativeLimitRate is the fraction of credit limits made negative when
+	// allowNegativeLimit is set
+	allowNegativeLimit bool
+	negativeLimitRate  float64
+	// partitionBy names the column used to split output into one directory per value
+	partitionBy   string
+	partitionDrop bool
+	// format names the Encoder of the output file
+	format string
+	// codebook is the csv file mapping the codes written for codebookColumns to
+	// their values
+	codebook        string
+	codebookColumns string
+	// toBQSchema is the BigQuery json schema file to write for the selected columns
+	toBQSchema string
+	// protoSchema is the file to write the .proto of -format protobuf rows to
+	protoSchema string
+	// compress names the codec of the output file
+	compress string
+	// outputEncoding is either utf-8 or latin1, onUnmappable is what latin1
+	// output does with runes it can't represent
+	outputEncoding string
+	onUnmappable   string
+	// lineEnding is either lf or crlf
+	lineEnding string
+	// columnsOrder is a file listing column names in their output order
+	columnsOrder string
+	// bqSchema is a BigQuery json schema file whose columns replace the csv columns
+	bqSchema string
+	// templateFile holds partial rows, the number of rows sets the count
+	templateFile string
+	// overrides replace the generator of a column with a faker function
+	overrides map[string]func(f *gofakeit.Faker) string
+	// normalizeLength forces card numbers to this many digits, zero keeps faker lengths
+	normalizeLength int
+	// expiryClustering is the fraction of expiries moved to one of expiryMonths
+	expiryClustering float64
+	expiryMonths     []time.Month
+	// countries adds the card holder's residency and nationality, which differ
+	// for expatRate of the holders
+	countries bool
+	expatRate float64
+	// notes adds a free-text notes column, embedPIIRate of which contain PII
+	notes        bool
+	embedPIIRate float64
+	// accountsHierarchy is the number of child cards per parent account, zero
+	// for no parent accounts
+	accountsHierarchy int
+	// numCustomers bounds the distinct card holders, zero for a new one per entry
+	numCustomers int
+	// dedupeCards regenerates entries until their card number is unused
+	dedupeCards bool
+	// maxRegenTotal bounds the entries regenerated over the whole run, 0 is unbounded
+	maxRegenTotal int
+	// testBins names the gateway whose published test card numbers are used
+	testBins string
+	// truncatePAN writes card numbers as BIN and last four only
+	truncatePAN bool
+	// lastFour adds the last four digits of the card number as a column
+	lastFour bool
+	// shuffle writes entries in an order derived from shuffleSeed
+	shuffle     bool
+	shuffleSeed int64
+	// duplicateRate is the fraction of entries followed by a copy of an
+	// earlier one, duplicateColumn flags the copies
+	duplicateRate   float64
+	duplicateColumn bool
+	// batchMarker writes a marker row after every batchSize entries
+	batchMarker bool
+	batchSize   int
+	// generationVersion selects how columns draw from the seed
+	generationVersion int
+	// banner writes a comment line before the csv header
+	banner       bool
+	bannerPrefix string
+	bannerText   string
+	// checkpoint is the file recording progress every checkpointEvery entries,
+	// resume continues the run it describes
+	checkpoint      string
+	checkpointEvery int
+	resume          bool
+	configHash      string
+	// maxDuration stops generation early once elapsed, zero means no limit
+	maxDuration time.Duration
+	// sample is the number of entries to print to stderr instead of writing a file
+	sample int
+}
+
+// enable turns on the optional columns of option, if a flag can do so alone
+func (c *genCfg) enable(option string) {
+	switch option {
+	case optionACH:
+		c.ach = true
+	case optionUUID:
+		c.uuid = true
+	case optionSplitName:
+		c.splitName = true
+	case optionBalance:
+		c.balance = true
+	case optionDuplicate:
+		c.duplicateColumn = true
+	case optionLastFour:
+		c.lastFour = true
+	case optionCountries:
+		c.countries = true
+	case optionNotes:
+		c.notes = true
+	}
+}
+
+// enabled reports whether the optional columns of option should be generated
+func (c genCfg) enabled(option string) bool {
+	switch option {
+	case optionACH:
+		return c.ach
+	case optionDispute:
+		return c.disputeRate > 0
+	case optionUUID:
+		return c.uuid
+	case optionSplitName:
+		return c.splitName
+	case optionDueDate:
+		return c.graceDays > 0
+	case optionBalance:
+		return c.balance
+	case optionDuplicate:
+		return c.duplicateColumn
+	case optionHierarchy:
+		return c.accountsHierarchy > 0
+	case optionLastFour:
+		return c.lastFour
+	case optionCountries:
+		return c.countries
+	case optionNotes:
+		return c.notes
+	default:
+		return true
 	}
 }
 
+// csv entry, values are in columns order
+type entry []string
+
 // issueBank generates a random issuing bank for a cc
 func issueBank(faker *gofakeit.Faker, ccName string) string {
 	switch ccName {
@@ -104,6 +246,33 @@ func issueBank(faker *gofakeit.Faker, ccName string) string {
 	}
 }
 
+// cardHolderName generates a random card holder name
+func cardHolderName(faker *gofakeit.Faker) string {
+	if len(holderNames) > 0 {
+		return faker.RandomString(holderNames)
+	}
+	return faker.Name()
+}
+
+// loadLines reads newline-delimited values from a file, skipping blank lines
+func loadLines(filename string) ([]string, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, l := range strings.Split(string(b), "\n") {
+		l = strings.TrimSpace(l)
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%s contains no values", filename)
+	}
+	return lines, nil
+}
+
 // ccShortCode generates a short code based on cc name
 // https://github.com/brianvoe/gofakeit/blob/master/data/payment.go#L19 for supported ccTypes
 func ccShortCode(ccName string) string {
@@ -137,72 +306,1035 @@ func ccShortCode(ccName string) string {
 	}
 }
 
-// generateEntry generates a CSV entry
-func generateEntry(faker *gofakeit.Faker) entry {
-	e := entry{}
-	minIssueT, err := time.Parse("2006-01-02", fmt.Sprintf("%s-01-01", minIssueYear))
+// cvvLength returns the number of cvv digits used by a card network
+func cvvLength(ccName string) int {
+	if ccName == "American Express" {
+		return 4
+	}
+	return 3
+}
+
+// luhnDigit returns the check digit that makes payload followed by it Luhn valid
+func luhnDigit(payload string) string {
+	sum := 0
+	for i := len(payload) - 1; i >= 0; i-- {
+		d := int(payload[i] - '0')
+		// double every second digit from the right, starting next to the check digit
+		if (len(payload)-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return strconv.Itoa((10 - sum%10) % 10)
+}
+
+// normalizeCardNumber pads or truncates number to length digits keeping its
+// prefix, then recomputes the Luhn check digit
+func normalizeCardNumber(faker *gofakeit.Faker, number string, length int) string {
+	if len(number) == length {
+		return number
+	}
+	payload := number[:len(number)-1]
+	if len(payload) >= length {
+		payload = payload[:length-1]
+	} else {
+		payload += faker.DigitN(uint(length - 1 - len(payload)))
+	}
+	return payload + luhnDigit(payload)
+}
+
+// truncatePAN keeps only the BIN and last four digits of a card number
+func truncatePAN(number string) string {
+	return number[:6] + "..." + number[len(number)-4:]
+}
+
+// expiryClusters is the number of months of the year clustered expiries fall in
+const expiryClusters = 3
+
+// expiryMonths draws the months of the year clustered expiries fall in, like
+// the batch renewal months of a portfolio
+func expiryMonths(seed int64) []time.Month {
+	r := rand.New(rand.NewSource(columnSeed(seed, "expiry months")))
+	months := make([]time.Month, expiryClusters)
+	for i, m := range r.Perm(12)[:expiryClusters] {
+		months[i] = time.Month(m + 1)
+	}
+	return months
+}
+
+// clusterExpiry moves expiry to the closest of months that is still 3-5
+// years after issued
+func clusterExpiry(expiry, issued time.Time, months []time.Month) time.Time {
+	first, last := issued.AddDate(3, 0, 0), issued.AddDate(5, 0, 0)
+	best, bestDiff := expiry, -1
+	for year := expiry.Year() - 1; year <= expiry.Year()+1; year++ {
+		for _, m := range months {
+			t := time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+			if t.Before(first) || t.After(last) {
+				continue
+			}
+			diff := int(t.Sub(expiry).Hours())
+			if diff < 0 {
+				diff = -diff
+			}
+			if bestDiff == -1 || diff < bestDiff {
+				best, bestDiff = t, diff
+			}
+		}
+	}
+	return best
+}
+
+// dueDate returns the payment due date of the first statement after issued,
+// graceDays after the statement's billing day
+func dueDate(issued time.Time, billingDay, graceDays int) time.Time {
+	statement := time.Date(issued.Year(), issued.Month(), billingDay, 0, 0, 0, 0, time.UTC)
+	if statement.Before(issued) {
+		statement = statement.AddDate(0, 1, 0)
+	}
+	return statement.AddDate(0, 0, graceDays)
+}
+
+// chance returns true with probability rate
+func chance(faker *gofakeit.Faker, rate float64) bool {
+	return faker.Rand.Float64() < rate
+}
+
+// accountBalance generates a balance for a credit limit. A rate fraction of
+// balances are over the limit by up to half of it, balances of negative limits
+// are always over it.
+func accountBalance(faker *gofakeit.Faker, limit int, rate float64) int {
+	if limit < 0 {
+		return faker.Number(0, -limit)
+	}
+	if rate > 0 && chance(faker, rate) {
+		return limit + faker.Number(1, limit/2+1)
+	}
+	return faker.Number(0, limit)
+}
+
+// notePII generates a recognizable email address, US social security number
+// or phone number to embed in notes
+func notePII(faker *gofakeit.Faker) string {
+	switch faker.Number(0, 2) {
+	case 0:
+		return faker.Email()
+	case 1:
+		ssn := faker.SSN()
+		return ssn[:3] + "-" + ssn[3:5] + "-" + ssn[5:]
+	default:
+		return faker.PhoneFormatted()
+	}
+}
+
+// routingNumber generates a 9 digit ABA routing number with a valid check digit
+func routingNumber(faker *gofakeit.Faker) string {
+	// first two digits are a federal reserve routing symbol: 01-12, 21-32, 61-72 or 80
+	prefix := faker.Number(1, 12) + faker.RandomInt([]int{0, 20, 60})
+	if faker.Number(1, 37) == 37 {
+		prefix = 80
+	}
+	digits := fmt.Sprintf("%02d%s", prefix, faker.DigitN(6))
+	weights := []int{3, 7, 1, 3, 7, 1, 3, 7}
+	sum := 0
+	for i, w := range weights {
+		sum += int(digits[i]-'0') * w
+	}
+	return digits + strconv.Itoa((10-sum%10)%10)
+}
+
+// generateEntry generates a CSV entry, using values from tmpl where present
+func generateEntry(f *fakers, cfg genCfg, tmpl rowTemplate) entry {
+	e := make(entry, len(columns))
+	if cfg.accountsHierarchy > 0 && f.parent == nil {
+		f.nextChild(cfg)
+	}
+	row := &Context{cfg: cfg, values: make(map[string]string, len(columns)), customers: f.customers, parent: f.parent,
+		constraints: f.constraints}
+	for _, g := range generators {
+		i := columnIndex(g.Name())
+		if !cfg.enabled(columns[i].option) {
+			continue
+		}
+		faker := f.column(g.Name())
+		// generate even when overridden so later columns can still rely on the row context
+		e[i] = g.Generate(faker, row)
+		if override, ok := cfg.overrides[g.Name()]; ok {
+			e[i] = override(faker)
+		}
+		if v, ok := tmpl[g.Name()]; ok {
+			e[i] = v
+		}
+		row.values[g.Name()] = e[i]
+	}
+	return e
+}
+
+// maxDedupeAttempts is the number of entries generated for a row before
+// dedupe-cards gives up on finding an unused card number
+const maxDedupeAttempts = 1000
+
+// cardDeduper regenerates entries whose card number was already generated
+type cardDeduper struct {
+	column int
+	seen   map[string]struct{}
+	// regenerated counts the entries regenerated in the run, up to maxTotal when set
+	regenerated int
+	maxTotal    int
+}
+
+func newCardDeduper(cfg genCfg) *cardDeduper {
+	if !cfg.dedupeCards {
+		return nil
+	}
+	return &cardDeduper{column: columnIndex("Card Number"), seen: map[string]struct{}{}, maxTotal: cfg.maxRegenTotal}
+}
+
+// nextEntry generates the next entry, regenerating it while its card number is
+// a duplicate when d is not nil. Regenerated entries stay the same child of
+// their parent account.
+func (d *cardDeduper) nextEntry(f *fakers, cfg genCfg, tmpl rowTemplate) (entry, error) {
+	f.nextChild(cfg)
+	if d == nil {
+		return generateEntry(f, cfg, tmpl), nil
+	}
+	for i := 0; i < maxDedupeAttempts; i++ {
+		if i > 0 {
+			d.regenerated++
+			if d.maxTotal > 0 && d.regenerated > d.maxTotal {
+				return nil, fmt.Errorf("max-regen-total %d exceeded: dedupe-cards regenerated %d entries for duplicate Card Number values after %d unique ones",
+					d.maxTotal, d.regenerated, len(d.seen))
+			}
+		}
+		e := generateEntry(f, cfg, tmpl)
+		if _, ok := d.seen[e[d.column]]; !ok {
+			d.seen[e[d.column]] = struct{}{}
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("no unused card number after %d attempts, card numbers are exhausted after %d unique ones", maxDedupeAttempts, len(d.seen))
+}
+
+// rowWriter writes csv records, the first record written is the header
+type rowWriter interface {
+	Write(record []string) error
+}
+
+// writeEntries writes the csv header and cfg.count entries to writer, or entries
+// until interrupted or max-duration is reached when cfg.count is negative. It
+// returns the number of entries generated.
+func writeEntries(writer rowWriter, cfg genCfg, selected []int) (int, error) {
+	written := 0
+	err := writer.Write(selectValues(columnNames(columns), selected))
 	if err != nil {
-		log.Fatal(err)
+		return written, err
+	}
+
+	var batches *batchWriter
+	if cfg.batchMarker {
+		batches = &batchWriter{writer: writer, size: cfg.batchSize, width: len(selected)}
+		writer = batches
+	}
+
+	var codebook *codebookWriter
+	if cfg.codebook != "" {
+		codebook, err = newCodebookWriter(writer, cfg, selected)
+		if err != nil {
+			return written, err
+		}
+		writer = codebook
+	}
+
+	var st *statsWriter
+	if cfg.stats != "" {
+		st = newStatsWriter(writer, selectValues(columnNames(columns), selected))
+		writer = st
+	}
+
+	var shuffled *shuffleWriter
+	if cfg.shuffle {
+		log.Print("shuffle buffers every entry in memory before writing")
+		shuffled = &shuffleWriter{writer: writer, seed: cfg.shuffleSeed}
+		writer = shuffled
+	}
+
+	if cfg.duplicateRate > 0 {
+		log.Print("duplicate-rate keeps every entry in memory to copy from")
+		writer = newDuplicateWriter(writer, cfg, selected)
+	}
+
+	var deadline time.Time
+	if cfg.maxDuration > 0 {
+		deadline = time.Now().Add(cfg.maxDuration)
+	}
+
+	var interrupted chan os.Signal
+	if cfg.count < 0 {
+		// unbounded runs stop on interrupt, keeping the entries written so far
+		interrupted = make(chan os.Signal, 1)
+		signal.Notify(interrupted, os.Interrupt)
+		defer signal.Stop(interrupted)
+	}
+
+	f := newFakers(cfg)
+	deduper := newCardDeduper(cfg)
+generate:
+	for ; cfg.count < 0 || written < cfg.count; written++ {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			log.Printf("max-duration %v reached, generated %s", cfg.maxDuration, progress(written, cfg.count))
+			break
+		}
+		select {
+		case <-interrupted:
+			log.Printf("interrupted, generated %s", progress(written, cfg.count))
+			break generate
+		default:
+		}
+		var tmpl rowTemplate
+		if written < len(rowTemplates) {
+			tmpl = rowTemplates[written]
+		}
+		e, err := deduper.nextEntry(f, cfg, tmpl)
+		if err != nil {
+			return written, err
+		}
+		err = writer.Write(selectValues(e, selected))
+		if err != nil {
+			return written, err
+		}
+	}
+
+	if shuffled != nil {
+		err = shuffled.flush()
+		if err != nil {
+			return written, err
+		}
+	}
+	if batches != nil {
+		err = batches.flush()
+		if err != nil {
+			return written, err
+		}
+	}
+	if codebook != nil {
+		err = codebook.writeCodebook(cfg.codebook, cfg)
+		if err != nil {
+			return written, err
+		}
+	}
+	if st != nil {
+		s := st.stats()
+		s.GenerationVersion = cfg.generationVersion
+		s.Encoding = cfg.outputEncoding
+		return written, writeStats(cfg.stats, s)
+	}
+	return written, nil
+}
+
+// progress describes how many of count entries were generated
+func progress(written, count int) string {
+	if count < 0 {
+		return fmt.Sprintf("%d entries", written)
+	}
+	return fmt.Sprintf("%d of %d entries", written, count)
+}
+
+// logSummary reports what a run wrote to name
+func logSummary(cfg genCfg, written int, name string) {
+	switch {
+	case cfg.count == 0:
+		log.Printf("count is 0, wrote only the header to %s", name)
+	case cfg.count < 0:
+		log.Printf("unbounded run wrote %d entries to %s", written, name)
+	default:
+		log.Printf("wrote %d entries to %s", written, name)
 	}
-	maxIssueT, err := time.Parse("2006-01-02", fmt.Sprintf("%s-01-01", maxIssueYear))
+}
+
+// batchMarker is the first value of the rows ending each batch
+const batchMarker = "__BATCH_END__"
+
+// batchWriter writes a marker row after every size rows and after the last row.
+// The marker row holds batchMarker, the batch number and its row count.
+type batchWriter struct {
+	writer rowWriter
+	size   int
+	width  int
+	batch  int
+	rows   int
+}
+
+func (b *batchWriter) Write(record []string) error {
+	err := b.writer.Write(record)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	// issued between min/max issue time
-	issueTime := faker.DateRange(minIssueT, maxIssueT)
-	e.issueDate = issueTime.Format("01/2006")
-	e.cardHolderName = faker.Name()
-	cc := faker.CreditCard()
-	e.cvv = cc.Cvv
-	e.cardNumber = cc.Number
-	e.cardTypeFullName = cc.Type
-	e.cardTypeCode = ccShortCode(cc.Type)
-	// expiry is 3-5 years after issue
-	expiryTime := faker.DateRange(issueTime.AddDate(3, 0, 0), issueTime.AddDate(5, 0, 0))
-	e.expiryDate = expiryTime.Format("01/2006")
-	e.issuingBank = issueBank(faker, cc.Type)
-	e.billingDate = strconv.Itoa(faker.Number(1, 27))
-	// 4 digit num
-	e.cardPin = strconv.Itoa(faker.Number(1000, 9999))
-	e.limit = strconv.Itoa(faker.Number(minCreditLimit, maxCreditLimit))
-	return e
+	b.rows++
+	if b.rows == b.size {
+		return b.flush()
+	}
+	return nil
+}
+
+// flush ends the current batch if it has any rows
+func (b *batchWriter) flush() error {
+	if b.rows == 0 {
+		return nil
+	}
+	b.batch++
+	marker := make([]string, b.width)
+	marker[0] = batchMarker
+	if b.width > 1 {
+		marker[1] = strconv.Itoa(b.batch)
+	}
+	if b.width > 2 {
+		marker[2] = strconv.Itoa(b.rows)
+	}
+	b.rows = 0
+	return b.writer.Write(marker)
+}
+
+// shuffleWriter buffers rows and writes them in an order derived from seed, so
+// the row contents don't depend on the shuffle
+type shuffleWriter struct {
+	writer rowWriter
+	seed   int64
+	rows   [][]string
+}
+
+func (s *shuffleWriter) Write(record []string) error {
+	s.rows = append(s.rows, record)
+	return nil
+}
+
+// flush writes the buffered rows in shuffled order
+func (s *shuffleWriter) flush() error {
+	r := rand.New(rand.NewSource(s.seed))
+	r.Shuffle(len(s.rows), func(i, j int) {
+		s.rows[i], s.rows[j] = s.rows[j], s.rows[i]
+	})
+	for _, row := range s.rows {
+		err := s.writer.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// duplicateWriter writes each row, followed with probability rate by a copy of a
+// random earlier row. Copies have "true" in the flag column if it is selected.
+type duplicateWriter struct {
+	writer rowWriter
+	rate   float64
+	rand   *rand.Rand
+	flag   int
+	rows   [][]string
+}
+
+func newDuplicateWriter(writer rowWriter, cfg genCfg, selected []int) *duplicateWriter {
+	d := &duplicateWriter{
+		writer: writer,
+		rate:   cfg.duplicateRate,
+		// separate from the fakers so duplicates don't change the generated entries
+		rand: rand.New(rand.NewSource(cfg.seed)),
+		flag: -1,
+	}
+	for i, c := range selected {
+		if columns[c].name == "Is Duplicate" {
+			d.flag = i
+		}
+	}
+	return d
+}
+
+func (d *duplicateWriter) Write(record []string) error {
+	err := d.writer.Write(record)
+	if err != nil {
+		return err
+	}
+	d.rows = append(d.rows, record)
+	if d.rand.Float64() >= d.rate {
+		return nil
+	}
+	dup := append([]string(nil), d.rows[d.rand.Intn(len(d.rows))]...)
+	if d.flag >= 0 {
+		dup[d.flag] = "true"
+	}
+	return d.writer.Write(dup)
 }
 
-func parseFlags() genCfg {
+// newCSVWriter returns a csv writer using the configured line ending
+func newCSVWriter(w io.Writer, cfg genCfg) *csv.Writer {
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = cfg.lineEnding == "crlf"
+	return writer
+}
+
+// writeBanner writes the banner comment line to w if enabled
+func writeBanner(w io.Writer, cfg genCfg) error {
+	if !cfg.banner {
+		return nil
+	}
+	text := cfg.bannerText
+	if text == "" {
+		text = fmt.Sprintf("generated by sample-cc-generator seed=%d count=%d", cfg.seed, cfg.count)
+	}
+	newline := "\n"
+	if cfg.lineEnding == "crlf" {
+		newline = "\r\n"
+	}
+	_, err := fmt.Fprintf(w, "%s %s%s", cfg.bannerPrefix, text, newline)
+	return err
+}
+
+// tableWriter writes records as aligned columns
+type tableWriter struct {
+	w *tabwriter.Writer
+}
+
+func (t tableWriter) Write(record []string) error {
+	_, err := fmt.Fprintln(t.w, strings.Join(record, "\t"))
+	return err
+}
+
+// writeSample writes cfg.sample entries to w as a table
+func writeSample(w io.Writer, cfg genCfg, selected []int) error {
+	cfg.count = cfg.sample
+	cfg.stats = ""
+	cfg.codebook = ""
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, err := writeEntries(tableWriter{tw}, cfg, selected)
+	if err != nil {
+		return err
+	}
+	return tw.Flush()
+}
+
+// writeFile calls write with a temporary file which is renamed to filename only
+// once write succeeds, so a failed run never leaves a partial file behind
+func writeFile(filename string, write func(io.Writer) error) error {
+	return writeFileMode(filename, 0755, write)
+}
+
+// writeFileMode is writeFile creating the file with permissions perm
+func writeFileMode(filename string, perm os.FileMode, write func(io.Writer) error) error {
+	tmp := filename + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+var placeholder = regexp.MustCompile(`{([^{}]*)}`)
+
+// renderFilename replaces the {date}, {seed}, {shard} and {format}
+// placeholders of tmpl, failing on any other placeholder
+func renderFilename(tmpl string, date time.Time, seed int64, shard int, format string) (string, error) {
+	values := map[string]string{
+		"date":   date.Format("20060102"),
+		"seed":   strconv.FormatInt(seed, 10),
+		"shard":  strconv.Itoa(shard),
+		"format": format,
+	}
+	var err error
+	name := placeholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		v, ok := values[m[1:len(m)-1]]
+		if !ok && err == nil {
+			err = fmt.Errorf("unknown placeholder %s in filename template %q", m, tmpl)
+		}
+		return v
+	})
+	return name, err
+}
+
+// envPrefix prefixes the environment variables flags are read from
+const envPrefix = "SDWCC_"
+
+// bindEnv sets the flags of fs that weren't passed from environment variables
+// named after them, e.g. SDWCC_COUNT for -count and SDWCC_EXCLUDE_FIELDS for
+// -exclude-fields, so flags take precedence over the environment
+func bindEnv(fs *flag.FlagSet) error {
+	passed := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		passed[f.Name] = true
+	})
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if passed[f.Name] || err != nil {
+			return
+		}
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if v, ok := os.LookupEnv(name); ok {
+			if serr := fs.Set(f.Name, v); serr != nil {
+				err = fmt.Errorf("invalid value %q for %s: %v", v, name, serr)
+			}
+		}
+	})
+	return err
+}
+
+func parseFlags(args []string) genCfg {
 	var c genCfg
 	flag.Int64Var(&c.seed, "seed", 1, "Random seed for generator. Defaults to 1")
-	flag.IntVar(&c.count, "count", 100, "Number of entries to generate. Defaults to 100")
-	flag.StringVar(&c.filename, "filename", "", "Filename to write csv data. Defaults to data-${count}.csv")
-	flag.Parse()
+	flag.IntVar(&c.count, "count", 100, "Number of entries to generate. 0 writes only the header, a negative count generates until interrupted or max-duration is reached. Defaults to 100")
+	flag.StringVar(&c.filename, "filename", "", "Filename to write data. Defaults to data-${count}.${format}")
+	flag.StringVar(&c.outputDir, "output-dir", "", "Directory to write the csv file or partitions to, created if missing. Defaults to the current directory")
+	filenameTemplate := flag.String("filename-template", "", "Filename with {date}, {seed}, {shard} and {format} placeholders, e.g. cards-{date}-{seed}-{shard}.{format}. Output is a single shard, 0")
+	flag.StringVar(&c.partitionBy, "partition-by", "", "Column to partition output by. Writes ${filename without extension}/${column}=${value}/part-0.csv files, empty values to ${column}="+defaultPartition)
+	flag.StringVar(&c.columnsOrder, "columns-order", "", "Newline-delimited file of column names pinning their output order. Unlisted columns follow in their default order")
+	flag.StringVar(&c.bqSchema, "from-bq-schema", "", "BigQuery json schema file to generate columns for. Columns named like a built-in column reuse its values, which must be of the same type, a STRING, or a DATE for the issue and expiry months")
+	flag.StringVar(&c.templateFile, "template-file", "", "Csv file of partial rows, or - for stdin. Present values are used verbatim and one entry is generated per row, ignoring count")
+	flag.DurationVar(&c.maxDuration, "max-duration", 0, "Stop generating after this long, e.g. 30s, keeping the entries written so far. Defaults to no limit")
+	requireFakerVersion := flag.String("require-faker-version", "", "Fail unless the linked "+fakerModule+" version is this one, e.g. v6.9.0, so data doesn't drift between builds")
+	redactLogs := flag.Bool("redact-logs", true, "Mask anything resembling a card number, cvv or base64 blob in log messages")
+	var gen stringsFlag
+	flag.Var(&gen, "gen", "Override a column's generator with a faker function as column=FuncName, e.g. \"Card Holder's Name=FirstName\". Repeatable")
+	flag.IntVar(&c.normalizeLength, "normalize-length", 0, "Pad or truncate card numbers to this many digits (12-19) keeping them Luhn valid. Numbers no longer follow their network's lengths")
+	flag.StringVar(&c.testBins, "test-bins", "", "Draw card numbers from a payment gateway's published test cards, accepted by its sandbox: "+strings.Join(testCardGateways(), ", "))
+	flag.BoolVar(&c.dedupeCards, "dedupe-cards", false, "Regenerate entries whose card number was already generated. Keeps every card number in memory, about 80 bytes each")
+	flag.IntVar(&c.maxRegenTotal, "max-regen-total", 0, "Fail the run once more than this many entries were regenerated by dedupe-cards, instead of slowing down as card numbers run out. Defaults to unbounded")
+	flag.BoolVar(&c.lastFour, "emit-last-four", false, "Add a Last Four column with the last four digits of the card number, which isn't PII")
+	flag.BoolVar(&c.truncatePAN, "truncate-pan", false, "Write card numbers as ${first 6}...${last 4} instead of the full number")
+	flag.BoolVar(&c.shuffle, "shuffle", false, "Write entries in a random order without changing their contents. Buffers every entry in memory")
+	flag.Int64Var(&c.shuffleSeed, "shuffle-seed", 1, "Random seed for the shuffle order. Defaults to 1")
+	flag.Float64Var(&c.duplicateRate, "duplicate-rate", 0, "Fraction of entries followed by an exact copy of a random earlier entry, e.g. 0.01. Keeps every entry in memory, can't be combined with dedupe-cards. Defaults to 0")
+	flag.BoolVar(&c.duplicateColumn, "duplicate-column", false, "Add an Is Duplicate column, true for the copies written by duplicate-rate")
+	flag.BoolVar(&c.batchMarker, "batch-marker", false, "Write a "+batchMarker+" row, with the batch number and row count, after every batch-size entries. Requires the csv format")
+	flag.IntVar(&c.batchSize, "batch-size", 1000, "Entries per batch for batch-marker. Defaults to 1000")
+	flag.IntVar(&c.generationVersion, "generation-version", generationShared, "How columns draw random values from the seed. 1 shares one stream between all columns, 2 gives each column its own stream so changes to one column don't shift the others")
+	flag.BoolVar(&c.banner, "banner", false, "Write a comment line before the csv header. Off by default to keep strict csv")
+	flag.StringVar(&c.bannerPrefix, "banner-prefix", "#", "Prefix of the banner comment line")
+	flag.StringVar(&c.bannerText, "banner-text", "", "Text of the banner comment line. Defaults to the generator, seed and count")
+	flag.StringVar(&c.checkpoint, "checkpoint", "", "File recording progress so an interrupted run can be continued with resume")
+	flag.IntVar(&c.checkpointEvery, "checkpoint-every", 10000, "Entries between checkpoints. Defaults to 10000")
+	flag.BoolVar(&c.resume, "resume", false, "Continue the interrupted run recorded in checkpoint, which must use the same flags")
+	flag.IntVar(&c.sample, "sample", 0, "Print this many entries to stderr and exit without writing files")
+	flag.StringVar(&c.format, "format", "csv", "Output format: "+strings.Join(encoderFormats(), ", "))
+	flag.StringVar(&c.codebook, "codebook", "", "Filename to write a csv lookup table of codebook-columns codes to. Values of those columns are written as integer codes")
+	flag.StringVar(&c.codebookColumns, "codebook-columns", "Card Type Full Name,Issuing Bank", "Comma separated list of low-cardinality columns to write as codes with codebook")
+	flag.StringVar(&c.toBQSchema, "to-bq-schema", "", "Filename to write a BigQuery json schema of the selected columns, with snake case names")
+	flag.StringVar(&c.protoSchema, "proto-schema", "", "Filename to write the proto3 Entry message of the selected columns, for decoding -format protobuf")
+	flag.StringVar(&c.outputEncoding, "output-encoding", encodingUTF8, "Character encoding of the output file, utf-8 or latin1 for ISO-8859-1 consumers")
+	flag.StringVar(&c.onUnmappable, "on-unmappable", unmappableReplace, "What latin1 output does with characters it can't represent: replace them with '?' or error")
+	flag.StringVar(&c.compress, "compress", "none", "Compression of the output file, appending its extension to the filename: "+strings.Join(codecNames(), ", "))
+	flag.StringVar(&c.lineEnding, "line-ending", "lf", "Line ending of csv rows, lf or crlf")
+	flag.BoolVar(&c.partitionDrop, "partition-drop", false, "Drop the partition column from partitioned rows")
+	flag.StringVar(&c.catalog, "catalog", "", "Filename to write a column catalog. Written as json for .json files, csv otherwise")
+	flag.StringVar(&c.stats, "stats", "", "Filename to write per column non-null counts, distinct counts and checksums as json")
+	flag.StringVar(&c.commit, "commit", "", "Filename to write a salted sha256 commitment of the output to, checked later with verify-commitment")
+	flag.StringVar(&c.commitSalt, "commit-salt", "", "Filename to write the secret commitment salt to. Defaults to ${commit}.salt")
+	flag.StringVar(&c.dlpInfoTypes, "dlp-info-types", "", "Filename to write the Cloud DLP infoTypes of each sensitive column as json, to configure an inspection job")
+	flag.StringVar(&c.profile, "profile", "", "Curated column set to write, enabling the optional columns it lists: "+strings.Join(profileNames(), ", ")+". exclude-fields and columns-order still apply")
+	flag.StringVar(&c.exclude, "exclude-fields", "", "Comma separated list of columns to leave out of the output and catalog")
+	flag.StringVar(&c.namesFile, "names-file", "", "Newline-delimited file of card holder names to draw from. Defaults to faker names")
+	flag.BoolVar(&c.ach, "ach", false, "Add ACH routing and account number columns")
+	flag.Float64Var(&c.disputeRate, "dispute-rate", 0, "Fraction of entries flagged in a Disputed column, e.g. 0.015. Defaults to no column")
+	flag.BoolVar(&c.uuid, "uuid", false, "Add a Customer UUID column derived from the card holder's name and customer. Card holders with the same name get different uuids unless they are the same num-customers customer")
+	uuidNamespace := flag.String("uuid-namespace", defaultUUIDNamespace, "Namespace uuid for Customer UUID values")
+	flag.Float64Var(&c.expiryClustering, "expiry-clustering", 0, fmt.Sprintf("Fraction of Expiry Date values moved to the closest of %d renewal months of the year drawn from the seed, e.g. 0.6. Defaults to uniform expiries", expiryClusters))
+	flag.BoolVar(&c.countries, "countries", false, "Add Residency Country and Nationality columns with the card holder's country codes")
+	flag.Float64Var(&c.expatRate, "expat-rate", 0, "Fraction of card holders whose nationality differs from their residency country, e.g. 0.05. Defaults to 0")
+	flag.BoolVar(&c.notes, "notes", false, "Add a free-text Notes column and a Notes PII column flagging notes with embedded PII")
+	flag.Float64Var(&c.embedPIIRate, "embed-pii-rate", 0, "Fraction of notes embedding an email address, US SSN or phone number, for testing DLP scanners, e.g. 0.1. Defaults to 0")
+	flag.IntVar(&c.accountsHierarchy, "accounts-hierarchy", 0, "Group every this many entries as child cards of a parent account, adding Parent Account ID and Parent Credit Limit columns. Children share the parent's issuing bank and split its credit limit")
+	flag.IntVar(&c.numCustomers, "num-customers", 0, "Draw card holders from this many customers so they hold several cards. Defaults to a new card holder per entry")
+	flag.BoolVar(&c.splitName, "split-name", false, "Generate card holder names as separate first and last names and add First Name and Last Name columns")
+	flag.BoolVar(&c.fullName, "full-name", true, "Keep the combined Card Holder's Name column when using split-name")
+	flag.BoolVar(&c.balance, "balance", false, "Add a Balance column of amounts owed, at most the Credit Limit unless over-limit-rate is set")
+	flag.Float64Var(&c.overLimitRate, "over-limit-rate", 0, "Fraction of Balance values above the Credit Limit, by up to half of it, e.g. 0.02. Defaults to 0")
+	flag.BoolVar(&c.allowNegativeLimit, "allow-negative-limit", false, "Make a negative-limit-rate fraction of Credit Limit values negative, for stress tests")
+	flag.Float64Var(&c.negativeLimitRate, "negative-limit-rate", 0.01, "Fraction of negative Credit Limit values with allow-negative-limit. Defaults to 0.01")
+	flag.IntVar(&c.graceDays, "grace-days", 0, "Add a Due Date column this many days after the first billing date. Defaults to no column")
+	flag.Float64Var(&c.unknownIssuerRate, "unknown-issuer-rate", 0, "Fraction of entries with an empty Issuing Bank, e.g. 0.05. Defaults to 0")
+	flag.StringVar(&c.banksFile, "banks-file", "", "Newline-delimited file of issuing banks to draw from. Defaults to built-in banks")
+	// ExitOnError makes Parse exit instead of returning an error
+	_ = flag.CommandLine.Parse(args)
+	err := bindEnv(flag.CommandLine)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !*redactLogs {
+		log.SetOutput(os.Stderr)
+	}
+	err = checkFakerVersion(*requireFakerVersion, fakerVersion())
+	if err != nil {
+		log.Fatal(err)
+	}
+	c.configHash = configHash(flag.CommandLine)
+	if *filenameTemplate != "" {
+		if c.filename != "" {
+			log.Fatal("filename can't be combined with filename-template")
+		}
+		c.filename, err = renderFilename(*filenameTemplate, time.Now().UTC(), c.seed, 0, c.format)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 	if c.filename == "" {
-		c.filename = fmt.Sprintf("data-%d.csv", c.count)
+		c.filename = fmt.Sprintf("data-%d.%s", c.count, c.format)
+		if c.count < 0 {
+			c.filename = "data-unbounded." + c.format
+		}
+	}
+	if c.outputDir != "" {
+		c.filename = filepath.Join(c.outputDir, c.filename)
+	}
+	comp, ok := codecs[c.compress]
+	if !ok {
+		log.Fatalf("compress must be one of %s, got %q", strings.Join(codecNames(), ", "), c.compress)
+	}
+	c.filename += comp.ext
+	if c.commit != "" && c.commitSalt == "" {
+		c.commitSalt = c.commit + ".salt"
+	}
+	c.uuidNamespace, err = parseUUID(*uuidNamespace)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c.overrides, err = parseOverrides(gen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if c.normalizeLength != 0 && (c.normalizeLength < 12 || c.normalizeLength > 19) {
+		log.Fatalf("normalize-length must be between 12 and 19, got %d", c.normalizeLength)
+	}
+	if _, ok := testCards[c.testBins]; c.testBins != "" && !ok {
+		log.Fatalf("test-bins must be one of %s, got %q", strings.Join(testCardGateways(), ", "), c.testBins)
+	}
+	if c.testBins != "" && c.normalizeLength != 0 {
+		log.Fatal("test-bins can't be combined with normalize-length")
+	}
+	if c.profile != "" {
+		names, ok := profiles[c.profile]
+		if !ok {
+			log.Fatalf("profile must be one of %s, got %q", strings.Join(profileNames(), ", "), c.profile)
+		}
+		if c.bqSchema != "" {
+			log.Fatal("profile can't be combined with from-bq-schema")
+		}
+		for _, name := range names {
+			c.enable(columns[columnIndex(name)].option)
+		}
+	}
+	if c.expiryClustering < 0 || c.expiryClustering > 1 {
+		log.Fatalf("expiry-clustering must be between 0 and 1, got %v", c.expiryClustering)
+	}
+	c.expiryMonths = expiryMonths(c.seed)
+	if c.maxRegenTotal < 0 {
+		log.Fatalf("max-regen-total must not be negative, got %d", c.maxRegenTotal)
+	}
+	if c.maxRegenTotal > 0 && !c.dedupeCards {
+		log.Fatal("max-regen-total requires dedupe-cards")
+	}
+	if c.expatRate < 0 || c.expatRate > 1 {
+		log.Fatalf("expat-rate must be between 0 and 1, got %v", c.expatRate)
+	}
+	if c.expatRate > 0 && !c.countries {
+		log.Fatal("expat-rate requires countries")
+	}
+	if c.embedPIIRate < 0 || c.embedPIIRate > 1 {
+		log.Fatalf("embed-pii-rate must be between 0 and 1, got %v", c.embedPIIRate)
+	}
+	if c.embedPIIRate > 0 && !c.notes {
+		log.Fatal("embed-pii-rate requires notes")
+	}
+	if c.accountsHierarchy < 0 {
+		log.Fatalf("accounts-hierarchy must not be negative, got %d", c.accountsHierarchy)
+	}
+	if c.numCustomers < 0 {
+		log.Fatalf("num-customers must not be negative, got %d", c.numCustomers)
+	}
+	if c.graceDays < 0 {
+		log.Fatalf("grace-days must not be negative, got %d", c.graceDays)
+	}
+	if !c.fullName && !c.splitName {
+		log.Fatal("full-name=false requires split-name")
+	}
+	if c.commit != "" && c.partitionBy != "" {
+		log.Fatal("commit can't be combined with partition-by")
+	}
+	if c.batchMarker && c.partitionBy != "" {
+		log.Fatal("batch-marker can't be combined with partition-by")
+	}
+	if c.codebook != "" && c.partitionBy != "" {
+		for _, name := range strings.Split(c.codebookColumns, ",") {
+			if strings.TrimSpace(name) == c.partitionBy {
+				log.Fatalf("partition-by column %q can't be one of the codebook-columns, the directories would be named by code", c.partitionBy)
+			}
+		}
+	}
+	if c.batchSize < 1 {
+		log.Fatalf("batch-size must be positive, got %d", c.batchSize)
+	}
+	if c.generationVersion != generationShared && c.generationVersion != generationStreams {
+		log.Fatalf("generation-version must be %d or %d, got %d", generationShared, generationStreams, c.generationVersion)
+	}
+	if strings.ContainsAny(c.bannerText+c.bannerPrefix, "\r\n") {
+		log.Fatal("banner-text and banner-prefix must be a single line")
+	}
+	if c.resume && c.checkpoint == "" {
+		log.Fatal("resume requires checkpoint")
+	}
+	if c.checkpoint != "" && c.count < 0 {
+		log.Fatal("checkpoint requires a count")
+	}
+	if c.checkpoint != "" && (c.partitionBy != "" || c.shuffle || c.duplicateRate > 0 || c.batchMarker || c.stats != "" || c.codebook != "" || c.maxDuration > 0) {
+		log.Fatal("checkpoint can't be combined with partition-by, shuffle, duplicate-rate, batch-marker, stats, codebook or max-duration")
+	}
+	if c.checkpointEvery < 1 {
+		log.Fatalf("checkpoint-every must be positive, got %d", c.checkpointEvery)
+	}
+	if c.maxDuration < 0 {
+		log.Fatalf("max-duration must not be negative, got %v", c.maxDuration)
+	}
+	if c.sample < 0 {
+		log.Fatalf("sample must not be negative, got %d", c.sample)
+	}
+	if _, ok := encoders[c.format]; !ok {
+		log.Fatalf("format must be one of %s, got %q", strings.Join(encoderFormats(), ", "), c.format)
+	}
+	if c.outputEncoding != encodingUTF8 && c.outputEncoding != encodingLatin1 {
+		log.Fatalf("output-encoding must be %s or %s, got %q", encodingUTF8, encodingLatin1, c.outputEncoding)
+	}
+	if c.onUnmappable != unmappableReplace && c.onUnmappable != unmappableError {
+		log.Fatalf("on-unmappable must be %s or %s, got %q", unmappableReplace, unmappableError, c.onUnmappable)
+	}
+	if c.outputEncoding != encodingUTF8 && (c.format == "protobuf" || c.partitionBy != "" || c.checkpoint != "") {
+		log.Fatal("output-encoding can't be combined with the protobuf format, partition-by or checkpoint")
+	}
+	if c.compress != "none" && (c.partitionBy != "" || c.checkpoint != "") {
+		log.Fatal("compress can't be combined with partition-by or checkpoint")
+	}
+	if c.format != "csv" && (c.partitionBy != "" || c.checkpoint != "" || c.banner || c.batchMarker) {
+		log.Fatal("partition-by, checkpoint, banner and batch-marker require the csv format")
+	}
+	if c.lineEnding != "lf" && c.lineEnding != "crlf" {
+		log.Fatalf("line-ending must be lf or crlf, got %q", c.lineEnding)
+	}
+	if c.unknownIssuerRate < 0 || c.unknownIssuerRate > 1 {
+		log.Fatalf("unknown-issuer-rate must be between 0 and 1, got %v", c.unknownIssuerRate)
+	}
+	if c.disputeRate < 0 || c.disputeRate > 1 {
+		log.Fatalf("dispute-rate must be between 0 and 1, got %v", c.disputeRate)
+	}
+	if c.duplicateRate < 0 || c.duplicateRate > 1 {
+		log.Fatalf("duplicate-rate must be between 0 and 1, got %v", c.duplicateRate)
+	}
+	if c.duplicateRate > 0 && c.dedupeCards {
+		log.Fatal("duplicate-rate can't be combined with dedupe-cards, the copies repeat card numbers")
+	}
+	if c.overLimitRate < 0 || c.overLimitRate > 1 {
+		log.Fatalf("over-limit-rate must be between 0 and 1, got %v", c.overLimitRate)
+	}
+	if c.overLimitRate > 0 && !c.balance {
+		log.Fatal("over-limit-rate requires balance")
+	}
+	if c.negativeLimitRate < 0 || c.negativeLimitRate > 1 {
+		log.Fatalf("negative-limit-rate must be between 0 and 1, got %v", c.negativeLimitRate)
 	}
 	return c
 }
 
-func main() {
-	cfg := parseFlags()
+// subcommand of the generator binary
+type subcommand struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
 
-	f, err := os.OpenFile(cfg.filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
+var subcommands = []subcommand{
+	{"generate", "Generate csv data, the default when no subcommand is given", func(args []string) error {
+		return run(parseFlags(args))
+	}},
+	{"bench", "Benchmark generation without writing files", runBench},
+	{"schema-only", "Write the catalog and schema files of the generate flags without generating data", runSchemaOnly},
+	{"verify-commitment", "Check a revealed file and salt against a commitment written by -commit", runVerifyCommitment},
+}
+
+// usage prints the subcommands followed by the generate flags
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [subcommand] [flags]\n\nSubcommands:\n", os.Args[0])
+	for _, s := range subcommands {
+		fmt.Fprintf(out, "  %-18s %s\n", s.name, s.description)
+	}
+	fmt.Fprintf(out, "\nRun '%s <subcommand> -h' for the flags of a subcommand. Generate flags:\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	// card data can end up in error messages, redact unless disabled by -redact-logs=false
+	log.SetOutput(redactWriter{os.Stderr})
+	args := os.Args[1:]
+	cmd := subcommands[0]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		found := false
+		for _, s := range subcommands {
+			if s.name == args[0] {
+				cmd, found = s, true
+			}
+		}
+		if !found {
+			log.Fatalf("unknown subcommand %q, run with -h to list subcommands", args[0])
+		}
+		args = args[1:]
+	}
+	err := cmd.run(args)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer f.Close()
+}
+
+// run generates the configured output. Errors are returned rather than fatal so
+// open files are always flushed or cleaned up before exiting.
+func run(cfg genCfg) error {
+	cfg, selected, err := prepare(cfg)
+	if err != nil {
+		return err
+	}
 
-	writer := csv.NewWriter(f)
-	defer writer.Flush()
+	if cfg.sample > 0 {
+		return writeSample(os.Stderr, cfg, selected)
+	}
 
-	err = writer.Write(csvHeaders)
+	if cfg.outputDir != "" {
+		err = os.MkdirAll(cfg.outputDir, 0755)
+		if err != nil {
+			return err
+		}
+	}
+	var written int
+	if cfg.partitionBy != "" {
+		written, err = writePartitions(cfg, selected)
+	} else if cfg.checkpoint != "" {
+		written, err = writeResumable(cfg, selected)
+	} else {
+		err = writeFile(cfg.filename, func(w io.Writer) error {
+			return compressed(w, cfg.compress, func(w io.Writer) error {
+				return encoded(w, cfg.outputEncoding, cfg.onUnmappable, func(w io.Writer) error {
+					written, err = writeEncoded(w, cfg, selected)
+					return err
+				})
+			})
+		})
+	}
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	if cfg.partitionBy != "" {
+		logSummary(cfg, written, "partitions of "+cfg.filename)
+	} else {
+		logSummary(cfg, written, cfg.filename)
 	}
 
-	faker := gofakeit.New(cfg.seed)
-	for i := 0; i < cfg.count; i++ {
-		e := generateEntry(faker)
-		err = writer.Write(e.strSlice())
+	if cfg.commit != "" {
+		err = writeCommitment(cfg.filename, cfg.commit, cfg.commitSalt)
 		if err != nil {
-			log.Fatal(err)
+			return err
+		}
+	}
+	return writeSchemas(cfg, selected)
+}
+
+// prepare loads the files named in cfg and selects the output columns. The
+// returned config has the count of the template file, if any.
+func prepare(cfg genCfg) (genCfg, []int, error) {
+	var err error
+	if cfg.namesFile != "" {
+		holderNames, err = loadLines(cfg.namesFile)
+		if err != nil {
+			return cfg, nil, err
+		}
+	}
+	if cfg.banksFile != "" {
+		issueBanks, err = loadLines(cfg.banksFile)
+		if err != nil {
+			return cfg, nil, err
+		}
+	}
+
+	if cfg.columnsOrder != "" {
+		columnsOrder, err = loadLines(cfg.columnsOrder)
+		if err != nil {
+			return cfg, nil, err
+		}
+	}
+	if cfg.bqSchema != "" {
+		err = loadBQSchema(cfg.bqSchema, cfg)
+		if err != nil {
+			return cfg, nil, err
 		}
 	}
+	if cfg.templateFile != "" {
+		rowTemplates, err = loadTemplates(cfg.templateFile, cfg)
+		if err != nil {
+			return cfg, nil, err
+		}
+		cfg.count = len(rowTemplates)
+	}
+
+	var exclude []string
+	if cfg.exclude != "" {
+		exclude = strings.Split(cfg.exclude, ",")
+	}
+	if !cfg.fullName {
+		exclude = append(exclude, "Card Holder's Name")
+	}
+	selected, err := selectColumns(cfg, exclude)
+	return cfg, selected, err
+}
+
+// writeSchemas writes the schema and metadata files describing the selected columns
+func writeSchemas(cfg genCfg, selected []int) error {
+	if cfg.protoSchema != "" {
+		err := writeProtoSchema(cfg.protoSchema, selected)
+		if err != nil {
+			return err
+		}
+	}
+	if cfg.toBQSchema != "" {
+		err := writeBQSchema(cfg.toBQSchema, selected)
+		if err != nil {
+			return err
+		}
+	}
+	if cfg.dlpInfoTypes != "" {
+		err := writeDLPInfoTypes(cfg.dlpInfoTypes, buildDLPInfoTypes(selected))
+		if err != nil {
+			return err
+		}
+	}
+	if cfg.catalog != "" {
+		// example values are taken from the first entry for the seed
+		example := generateEntry(newFakers(cfg), cfg, nil)
+		return writeCatalog(cfg.catalog, buildCatalog(cfg, example, selected))
+	}
+	return nil
+}
+
+// runSchemaOnly writes the schema and metadata files of the generate flags
+// without generating data
+func runSchemaOnly(args []string) error {
+	cfg, selected, err := prepare(parseFlags(args))
+	if err != nil {
+		return err
+	}
+	if cfg.catalog == "" && cfg.protoSchema == "" && cfg.toBQSchema == "" && cfg.dlpInfoTypes == "" {
+		return fmt.Errorf("schema-only needs at least one of catalog, proto-schema, to-bq-schema or dlp-info-types")
+	}
+	return writeSchemas(cfg, selected)
 }
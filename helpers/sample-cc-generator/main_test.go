@@ -0,0 +1,746 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the package state runs and tests change, restored by resetState
+var (
+	builtinColumns    = append([]column(nil), columns...)
+	builtinGenerators = append([]FieldGenerator(nil), generators...)
+	builtinBanks      = issueBanks
+)
+
+func resetState() {
+	columns = append([]column(nil), builtinColumns...)
+	generators = append([]FieldGenerator(nil), builtinGenerators...)
+	issueBanks = builtinBanks
+	holderNames = nil
+	columnsOrder = nil
+	rowTemplates = nil
+	schemaColumns = nil
+}
+
+// parseArgs parses args like the generate subcommand, on a fresh flag set and
+// package state
+func parseArgs(t *testing.T, args ...string) genCfg {
+	t.Helper()
+	resetState()
+	t.Cleanup(resetState)
+	flag.CommandLine = flag.NewFlagSet("generate", flag.ExitOnError)
+	return parseFlags(args)
+}
+
+// generateFile runs generate with args, writing to a file in a temporary
+// directory, and returns the name of the file
+func generateFile(t *testing.T, args ...string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "data.csv")
+	cfg := parseArgs(t, append([]string{"-filename", filename}, args...)...)
+	err := run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// generateCSV runs generate with args and returns the csv header and rows
+// keyed by column name
+func generateCSV(t *testing.T, args ...string) ([]string, []map[string]string) {
+	t.Helper()
+	return readCSV(t, generateFile(t, args...))
+}
+
+func readCSV(t *testing.T, filename string) ([]string, []map[string]string) {
+	t.Helper()
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) == 0 {
+		t.Fatalf("%s has no header", filename)
+	}
+	var rows []map[string]string
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(record))
+		for i, v := range record {
+			row[records[0][i]] = v
+		}
+		rows = append(rows, row)
+	}
+	return records[0], rows
+}
+
+// writeLines writes a newline-delimited file in dir and returns its name
+func writeLines(t *testing.T, dir, name string, lines ...string) string {
+	t.Helper()
+	filename := filepath.Join(dir, name)
+	err := os.WriteFile(filename, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestDataFiles(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{"Ada Lovelace", "Alan Turing", "Grace Hopper"}
+	namesFile := writeLines(t, dir, "names.txt", names...)
+	banksFile := writeLines(t, dir, "banks.txt", "First Test Bank")
+	_, rows := generateCSV(t, "-count", "200", "-names-file", namesFile, "-banks-file", banksFile)
+
+	inFile := map[string]bool{}
+	for _, name := range names {
+		inFile[name] = true
+	}
+	// these networks always have the same issuing bank
+	networkBanks := map[string]string{
+		"American Express": "American Express",
+		"Diners Club":      "Diners Club International",
+		"JCB":              "Japan Credit Bureau",
+		"Discover":         "Discover",
+	}
+	for i, row := range rows {
+		if name := row["Card Holder's Name"]; !inFile[name] {
+			t.Errorf("row %d: name %q is not in the names file", i, name)
+		}
+		want, ok := networkBanks[row["Card Type Full Name"]]
+		if !ok {
+			want = "First Test Bank"
+		}
+		if bank := row["Issuing Bank"]; bank != want {
+			t.Errorf("row %d: %s issuing bank is %q, want %q", i, row["Card Type Full Name"], bank, want)
+		}
+	}
+}
+
+func TestLoadLines(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{"trims blank lines", "a\n\n  b  \n", []string{"a", "b"}, false},
+		{"empty", "\n\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(dir, tt.name)
+			err := os.WriteFile(filename, []byte(tt.content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadLines(filename)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadLines() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("loadLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantFile bool
+	}{
+		{"complete", nil, true},
+		{"error mid-run", errors.New("row 3 failed"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "data.csv")
+			err := writeFile(filename, func(w io.Writer) error {
+				_, err := io.WriteString(w, "partial,row\n")
+				if err != nil {
+					return err
+				}
+				return tt.err
+			})
+			if err != tt.err {
+				t.Fatalf("writeFile() error = %v, want %v", err, tt.err)
+			}
+			if _, err := os.Stat(filename); (err == nil) != tt.wantFile {
+				t.Errorf("%s exists = %v, want %v", filename, err == nil, tt.wantFile)
+			}
+			if _, err := os.Stat(filename + ".tmp"); err == nil {
+				t.Errorf("temp file %s.tmp was left behind", filename)
+			}
+		})
+	}
+}
+
+func TestRoutingNumber(t *testing.T) {
+	_, rows := generateCSV(t, "-count", "500", "-ach")
+	for i, row := range rows {
+		routing := row["Routing Number"]
+		if len(routing) != 9 || strings.Trim(routing, "0123456789") != "" {
+			t.Fatalf("row %d: routing number %q is not 9 digits", i, routing)
+		}
+		sum := 0
+		for j, w := range []int{3, 7, 1, 3, 7, 1, 3, 7, 1} {
+			sum += int(routing[j]-'0') * w
+		}
+		if sum%10 != 0 {
+			t.Errorf("row %d: routing number %q has an invalid check digit", i, routing)
+		}
+		prefix, _ := strconv.Atoi(routing[:2])
+		if !(prefix >= 1 && prefix <= 12 || prefix >= 21 && prefix <= 32 || prefix >= 61 && prefix <= 72 || prefix == 80) {
+			t.Errorf("row %d: routing number %q has an invalid routing symbol", i, routing)
+		}
+		if row["Account Number"] == "" {
+			t.Errorf("row %d: empty account number", i)
+		}
+	}
+}
+
+func TestLineEnding(t *testing.T) {
+	tests := []struct {
+		lineEnding string
+		want       string
+	}{
+		{"lf", "\n"},
+		{"crlf", "\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lineEnding, func(t *testing.T) {
+			b, err := os.ReadFile(generateFile(t, "-count", "3", "-banner", "-line-ending", tt.lineEnding))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// banner, header and rows
+			lines := strings.SplitAfter(string(b), "\n")
+			if lines[len(lines)-1] != "" {
+				t.Fatalf("output doesn't end with a line ending: %q", lines[len(lines)-1])
+			}
+			lines = lines[:len(lines)-1]
+			if len(lines) != 5 {
+				t.Fatalf("got %d lines, want 5", len(lines))
+			}
+			for i, l := range lines {
+				body := strings.TrimSuffix(l, tt.want)
+				if body == l || strings.ContainsAny(body, "\r\n") {
+					t.Errorf("line %d = %q, want it to end with %q only", i, l, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSample(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.csv")
+	cfg, selected, err := prepare(parseArgs(t, "-sample", "3", "-filename", filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	err = writeSample(&b, cfg, selected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// header and rows
+	if lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n"); len(lines) != 4 {
+		t.Errorf("sample printed %d lines, want 4:\n%s", len(lines), b.String())
+	}
+
+	stderr := os.Stderr
+	os.Stderr, err = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		os.Stderr.Close()
+		os.Stderr = stderr
+	}()
+	err = run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("sample created %s", filename)
+	}
+}
+
+// failingWriter fails writing the row at index fail, the header has index -1
+type failingWriter struct {
+	writer rowWriter
+	rows   int
+	fail   int
+}
+
+func (f *failingWriter) Write(record []string) error {
+	f.rows++
+	if f.rows-2 == f.fail {
+		return errors.New("injected error")
+	}
+	return f.writer.Write(record)
+}
+
+func TestErrorMidRun(t *testing.T) {
+	for _, k := range []int{0, 1, 7} {
+		t.Run(fmt.Sprintf("row %d", k), func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "data.csv")
+			cfg, selected, err := prepare(parseArgs(t, "-count", "10", "-filename", filename))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var written int
+			err = writeFile(filename, func(w io.Writer) error {
+				writer := csv.NewWriter(w)
+				written, err = writeEntries(&failingWriter{writer: writer, fail: k}, cfg, selected)
+				writer.Flush()
+				return err
+			})
+			if err == nil {
+				t.Fatal("writeEntries() succeeded despite the injected error")
+			}
+			if written != k {
+				t.Errorf("writeEntries() wrote %d entries before the error, want %d", written, k)
+			}
+			if _, err := os.Stat(filename); !os.IsNotExist(err) {
+				t.Errorf("%s exists after the error", filename)
+			}
+		})
+	}
+}
+
+// luhnValid reports whether number is a string of digits with a valid Luhn check digit
+func luhnValid(number string) bool {
+	if len(number) < 2 || strings.Trim(number, "0123456789") != "" {
+		return false
+	}
+	return luhnDigit(number[:len(number)-1]) == number[len(number)-1:]
+}
+
+func TestMaxDuration(t *testing.T) {
+	for _, count := range []string{"10000000", "-1"} {
+		t.Run("count "+count, func(t *testing.T) {
+			// readCSV fails on rows with a different number of fields than the header
+			header, rows := generateCSV(t, "-count", count, "-max-duration", "20ms")
+			if len(rows) == 0 || len(rows) >= 10000000 {
+				t.Errorf("got %d rows, want a partial run", len(rows))
+			}
+			for i, row := range rows {
+				if row[header[len(header)-1]] == "" {
+					t.Errorf("row %d is incomplete: %v", i, row)
+				}
+			}
+		})
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	tests := []struct {
+		args []string
+		// rows is the number of rows wanted, -1 for a partial run of some rows
+		rows    int
+		summary string
+	}{
+		{[]string{"-count", "0"}, 0, "count is 0, wrote only the header to %[1]s"},
+		{[]string{"-count", "5"}, 5, "wrote %[2]d entries to %[1]s"},
+		{[]string{"-count", "-1", "-max-duration", "20ms"}, -1, "unbounded run wrote %[2]d entries to %[1]s"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			var buf bytes.Buffer
+			log.SetOutput(&buf)
+			t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+			filename := generateFile(t, tt.args...)
+			header, rows := readCSV(t, filename)
+			if len(header) == 0 {
+				t.Fatal("no header written")
+			}
+			if tt.rows == -1 && len(rows) == 0 {
+				t.Error("got no rows, want a partial run")
+			} else if tt.rows != -1 && len(rows) != tt.rows {
+				t.Errorf("got %d rows, want %d", len(rows), tt.rows)
+			}
+			var want string
+			if tt.rows == 0 {
+				want = fmt.Sprintf(tt.summary, filename)
+			} else {
+				want = fmt.Sprintf(tt.summary, filename, len(rows))
+			}
+			if got := buf.String(); !strings.Contains(got, want+"\n") {
+				t.Errorf("logged %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	lines := func(args ...string) []string {
+		b, err := os.ReadFile(generateFile(t, append([]string{"-count", "100"}, args...)...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(string(b), "\n")
+	}
+	plain, shuffled := lines(), lines("-shuffle")
+	if plain[0] != shuffled[0] {
+		t.Errorf("shuffled header = %q, want %q", shuffled[0], plain[0])
+	}
+	if strings.Join(plain, "\n") == strings.Join(shuffled, "\n") {
+		t.Error("shuffle didn't change the row order")
+	}
+	sort.Strings(plain)
+	sort.Strings(shuffled)
+	if strings.Join(plain, "\n") != strings.Join(shuffled, "\n") {
+		t.Error("shuffle changed the rows")
+	}
+	if other := lines("-shuffle", "-shuffle-seed", "2"); strings.Join(other, "\n") == strings.Join(lines("-shuffle"), "\n") {
+		t.Error("shuffle-seed didn't change the row order")
+	}
+}
+
+func TestBatchMarker(t *testing.T) {
+	filename := generateFile(t, "-count", "25", "-batch-marker", "-batch-size", "10")
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// markers follow every 10th data row and the last one
+	var markers [][]string
+	rows := 0
+	for _, record := range records[1:] {
+		if record[0] != batchMarker {
+			rows++
+			continue
+		}
+		if rows != 10 && rows != 20 && rows != 25 {
+			t.Errorf("marker after %d rows, want it after every 10 rows and the last row", rows)
+		}
+		markers = append(markers, record)
+	}
+	if rows != 25 {
+		t.Errorf("got %d data rows, want 25", rows)
+	}
+	want := [][]string{{"1", "10"}, {"2", "10"}, {"3", "5"}}
+	if len(markers) != len(want) {
+		t.Fatalf("got %d markers, want %d", len(markers), len(want))
+	}
+	for i, m := range markers {
+		if m[1] != want[i][0] || m[2] != want[i][1] {
+			t.Errorf("marker %d = batch %s with %s rows, want batch %s with %s rows", i, m[1], m[2], want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestBanner(t *testing.T) {
+	filename := generateFile(t, "-count", "3", "-seed", "5", "-banner")
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(b), "\n")
+	if want := "# generated by sample-cc-generator seed=5 count=3"; lines[0] != want {
+		t.Errorf("first line = %q, want the banner %q", lines[0], want)
+	}
+
+	// readers skipping comments, like the template loader, see the header and rows
+	templates, err := loadTemplates(filename, parseArgs(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 3 {
+		t.Errorf("got %d rows after the banner, want 3", len(templates))
+	}
+	for i, tmpl := range templates {
+		if tmpl["Card Number"] == "" {
+			t.Errorf("row %d has no card number", i)
+		}
+	}
+}
+
+func TestDueDate(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name       string
+		issued     time.Time
+		billingDay int
+		graceDays  int
+		want       time.Time
+	}{
+		{"same month", date(2020, time.March, 2), 10, 5, date(2020, time.March, 15)},
+		{"into next month", date(2020, time.March, 2), 20, 21, date(2020, time.April, 10)},
+		{"billed next month", date(2020, time.March, 25), 10, 21, date(2020, time.May, 1)},
+		{"leap february", date(2020, time.February, 1), 27, 2, date(2020, time.February, 29)},
+		{"into next year", date(2020, time.December, 28), 15, 25, date(2021, time.February, 9)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dueDate(tt.issued, tt.billingDay, tt.graceDays); !got.Equal(tt.want) {
+				t.Errorf("dueDate() = %s, want %s", got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+
+	_, rows := generateCSV(t, "-count", "200", "-grace-days", "23")
+	for i, row := range rows {
+		due, err := time.Parse("01/02/2006", row["Due Date"])
+		if err != nil {
+			t.Fatalf("row %d: %v", i, err)
+		}
+		if billed := due.AddDate(0, 0, -23).Day(); strconv.Itoa(billed) != row["Billing Date"] {
+			t.Errorf("row %d: due date %s isn't 23 days after billing day %s", i, row["Due Date"], row["Billing Date"])
+		}
+	}
+}
+
+// TestMain runs main instead of the tests in the child processes of runMain
+func TestMain(m *testing.M) {
+	if args := os.Getenv("SAMPLE_CC_GENERATOR_TEST_ARGS"); args != "" {
+		os.Args = append([]string{"sample-cc-generator"}, strings.Fields(args)...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs main with args in a child process, for checks of flags that
+// exit, and returns its output
+func runMain(t *testing.T, args string) (string, error) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), "SAMPLE_CC_GENERATOR_TEST_ARGS="+args)
+	cmd.Dir = t.TempDir()
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func TestSubcommandHelp(t *testing.T) {
+	tests := []struct {
+		args string
+		want []string
+	}{
+		{"-h", append([]string{"-filename"}, subcommandNames()...)},
+		{"generate -h", []string{"-filename", "-seed"}},
+		{"bench -h", []string{"-count", "-cpuprofile"}},
+		{"schema-only -h", []string{"-catalog", "-proto-schema"}},
+		{"verify-commitment -h", []string{"-commitment", "-salt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			out, err := runMain(t, tt.args)
+			if err != nil {
+				t.Fatalf("%v: %s", err, out)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("usage doesn't mention %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+func subcommandNames() []string {
+	var names []string
+	for _, s := range subcommands {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+func TestRenderFilename(t *testing.T) {
+	date := time.Date(2021, time.March, 4, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		tmpl    string
+		want    string
+		wantErr bool
+	}{
+		{"cards-{date}-{seed}-{shard}.{format}", "cards-20210304-42-3.json", false},
+		{"{seed}{seed}.csv", "4242.csv", false},
+		{"fixed.csv", "fixed.csv", false},
+		{"cards-{time}.csv", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			got, err := renderFilename(tt.tmpl, date, 42, 3, "json")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderFilename() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("renderFilename() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	dir := filepath.Join(t.TempDir(), "out")
+	cfg := parseArgs(t, "-output-dir", dir, "-filename-template", "cards-{seed}-{shard}.{format}", "-seed", "9", "-count", "1")
+	err := run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cards-9-0.csv")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFlagConflicts(t *testing.T) {
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"-count 200 -dedupe-cards -duplicate-rate 0.2", "duplicate-rate can't be combined with dedupe-cards"},
+		{"-count 5 -countries -codebook cb.csv -codebook-columns Nationality -partition-by Nationality", "can't be one of the codebook-columns"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			out, err := runMain(t, tt.args)
+			if err == nil || !strings.Contains(out, tt.want) {
+				t.Errorf("got error %v and output %q, want a failure with %q", err, out, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuplicateRate(t *testing.T) {
+	header, rows := generateCSV(t, "-count", "5000", "-duplicate-rate", "0.1", "-duplicate-column")
+	key := func(row map[string]string) string {
+		var values []string
+		for _, name := range header {
+			if name != "Is Duplicate" {
+				values = append(values, row[name])
+			}
+		}
+		return strings.Join(values, "\x00")
+	}
+	seen := map[string]bool{}
+	originals, duplicates := 0, 0
+	for i, row := range rows {
+		if row["Is Duplicate"] == "false" {
+			originals++
+			seen[key(row)] = true
+			continue
+		}
+		duplicates++
+		if !seen[key(row)] {
+			t.Errorf("row %d is flagged as a duplicate but isn't a copy of an earlier row", i)
+		}
+	}
+	if originals != 5000 {
+		t.Errorf("got %d original rows, want 5000", originals)
+	}
+	if got := float64(duplicates) / float64(originals); got < 0.085 || got > 0.115 {
+		t.Errorf("duplicate rate = %v, want 0.1", got)
+	}
+}
+
+// setenv sets an environment variable for the duration of the test
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+	old, ok := os.LookupEnv(key)
+	err := os.Setenv(key, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestBindEnv(t *testing.T) {
+	setenv(t, "SDWCC_COUNT", "1000")
+	setenv(t, "SDWCC_EXCLUDE_FIELDS", "Card PIN")
+	setenv(t, "SDWCC_SEED", "7")
+	tests := []struct {
+		name    string
+		args    []string
+		count   int
+		exclude string
+		seed    int64
+	}{
+		{"environment", nil, 1000, "Card PIN", 7},
+		{"flags override", []string{"-count", "5", "-seed", "3"}, 5, "Card PIN", 3},
+		{"explicit default overrides", []string{"-count", "10"}, 10, "Card PIN", 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			count := fs.Int("count", 10, "")
+			exclude := fs.String("exclude-fields", "", "")
+			seed := fs.Int64("seed", 1, "")
+			fs.Int("unset", 4, "")
+			err := fs.Parse(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = bindEnv(fs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *count != tt.count || *exclude != tt.exclude || *seed != tt.seed {
+				t.Errorf("count, exclude-fields, seed = %d, %q, %d, want %d, %q, %d", *count, *exclude, *seed, tt.count, tt.exclude, tt.seed)
+			}
+			if v := fs.Lookup("unset").Value.String(); v != "4" {
+				t.Errorf("flag without a variable = %s, want its default 4", v)
+			}
+		})
+	}
+
+	setenv(t, "SDWCC_COUNT", "many")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("count", 10, "")
+	err := bindEnv(fs)
+	if err == nil || !strings.Contains(err.Error(), "SDWCC_COUNT") {
+		t.Errorf("got error %v, want the invalid SDWCC_COUNT", err)
+	}
+
+	// the generate flags are bound too
+	setenv(t, "SDWCC_COUNT", "25")
+	cfg := parseArgs(t, "-seed", "3")
+	if cfg.count != 25 || cfg.seed != 3 {
+		t.Errorf("generate count, seed = %d, %d, want 25 from the environment and 3 from the flag", cfg.count, cfg.seed)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	gofakeit "github.com/brianvoe/gofakeit/v6"
+)
+
+// fakerFuncs are the faker functions a column generator can be overridden with
+var fakerFuncs = map[string]func(f *gofakeit.Faker) string{
+	"AchAccount":     (*gofakeit.Faker).AchAccount,
+	"AchRouting":     (*gofakeit.Faker).AchRouting,
+	"BS":             (*gofakeit.Faker).BS,
+	"City":           (*gofakeit.Faker).City,
+	"Company":        (*gofakeit.Faker).Company,
+	"Country":        (*gofakeit.Faker).Country,
+	"CreditCardCvv":  (*gofakeit.Faker).CreditCardCvv,
+	"CreditCardExp":  (*gofakeit.Faker).CreditCardExp,
+	"CreditCardType": (*gofakeit.Faker).CreditCardType,
+	"CurrencyShort":  (*gofakeit.Faker).CurrencyShort,
+	"Email":          (*gofakeit.Faker).Email,
+	"FirstName":      (*gofakeit.Faker).FirstName,
+	"Gender":         (*gofakeit.Faker).Gender,
+	"JobTitle":       (*gofakeit.Faker).JobTitle,
+	"LastName":       (*gofakeit.Faker).LastName,
+	"Name":           (*gofakeit.Faker).Name,
+	"Phone":          (*gofakeit.Faker).Phone,
+	"PhoneFormatted": (*gofakeit.Faker).PhoneFormatted,
+	"SSN":            (*gofakeit.Faker).SSN,
+	"State":          (*gofakeit.Faker).State,
+	"Street":         (*gofakeit.Faker).Street,
+	"URL":            (*gofakeit.Faker).URL,
+	"UUID":           (*gofakeit.Faker).UUID,
+	"Username":       (*gofakeit.Faker).Username,
+	"Word":           (*gofakeit.Faker).Word,
+	"Zip":            (*gofakeit.Faker).Zip,
+}
+
+// stringsFlag collects the values of a repeatable flag
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// fakerFuncNames returns the sorted names of fakerFuncs
+func fakerFuncNames() []string {
+	names := make([]string, 0, len(fakerFuncs))
+	for name := range fakerFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseOverrides resolves column=FuncName mappings against fakerFuncs
+func parseOverrides(mappings []string) (map[string]func(f *gofakeit.Faker) string, error) {
+	overrides := make(map[string]func(f *gofakeit.Faker) string, len(mappings))
+	for _, m := range mappings {
+		kv := strings.SplitN(m, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("gen %q must be column=FuncName", m)
+		}
+		name, fn := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if columnIndex(name) == -1 {
+			return nil, fmt.Errorf("unknown column %q in gen %q", name, m)
+		}
+		f, ok := fakerFuncs[fn]
+		if !ok {
+			return nil, fmt.Errorf("unknown faker function %q in gen %q, supported: %s", fn, m, strings.Join(fakerFuncNames(), ", "))
+		}
+		overrides[name] = f
+	}
+	return overrides, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenOverride(t *testing.T) {
+	_, rows := generateCSV(t, "-count", "20", "-gen", "Issuing Bank=Email", "-gen", "Card PIN = Zip")
+	for i, row := range rows {
+		if !strings.Contains(row["Issuing Bank"], "@") {
+			t.Errorf("row %d: Issuing Bank %q isn't an email", i, row["Issuing Bank"])
+		}
+		if len(row["Card PIN"]) != 5 {
+			t.Errorf("row %d: Card PIN %q isn't a zip code", i, row["Card PIN"])
+		}
+	}
+}
+
+func TestParseOverrides(t *testing.T) {
+	tests := []struct {
+		mapping string
+		wantErr bool
+	}{
+		{"Card Holder's Name=FirstName", false},
+		{"Card Holder's Name", true},
+		{"No Such Column=FirstName", true},
+		{"Card Holder's Name=NoSuchFunc", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mapping, func(t *testing.T) {
+			got, err := parseOverrides([]string{tt.mapping})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOverrides() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got["Card Holder's Name"] == nil {
+				t.Errorf("parseOverrides() = %v, want a Card Holder's Name override", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"container/list"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// maxOpenPartitions bounds the partition files kept open. The least recently
+	// written one is closed, and reopened for appending when it gets another row.
+	maxOpenPartitions = 64
+	// defaultPartition is the partition of empty values, named like hive's
+	defaultPartition = "__HIVE_DEFAULT_PARTITION__"
+)
+
+// partitionEscaper escapes characters that are not allowed in a hive style path segment
+var partitionEscaper = strings.NewReplacer("%", "%25", "/", "%2F", "\\", "%5C", "=", "%3D")
+
+// partition is a csv file holding the rows of a single partition value. It is
+// written to filename.tmp while open, f is nil once closed.
+type partition struct {
+	filename string
+	f        *os.File
+	writer   *csv.Writer
+	// lru is the element of the partition in the open list
+	lru *list.Element
+}
+
+// partitionWriter routes rows to one csv file per distinct value of a column.
+// The first record written is the header, which is repeated in every file.
+type partitionWriter struct {
+	cfg        genCfg
+	dir        string
+	column     int
+	drop       bool
+	header     []string
+	partitions map[string]*partition
+	// open lists the open partitions, most recently written first
+	open *list.List
+}
+
+func newPartitionWriter(cfg genCfg, dir string, column int, drop bool) *partitionWriter {
+	return &partitionWriter{
+		cfg:        cfg,
+		dir:        dir,
+		column:     column,
+		drop:       drop,
+		partitions: map[string]*partition{},
+		open:       list.New(),
+	}
+}
+
+// row returns record with the partition column removed if drop is set
+func (p *partitionWriter) row(record []string) []string {
+	if !p.drop {
+		return record
+	}
+	row := make([]string, 0, len(record)-1)
+	row = append(row, record[:p.column]...)
+	return append(row, record[p.column+1:]...)
+}
+
+func (p *partitionWriter) Write(record []string) error {
+	if p.header == nil {
+		p.header = record
+		return nil
+	}
+	value := record[p.column]
+	part, ok := p.partitions[value]
+	if !ok {
+		name := partitionEscaper.Replace(value)
+		if value == "" {
+			name = defaultPartition
+		}
+		dir := filepath.Join(p.dir, fmt.Sprintf("%s=%s", partitionEscaper.Replace(p.header[p.column]), name))
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return err
+		}
+		part = &partition{filename: filepath.Join(dir, "part-0.csv")}
+		p.partitions[value] = part
+		err = p.openFile(part, os.O_RDWR|os.O_CREATE|os.O_TRUNC)
+		if err != nil {
+			return err
+		}
+		err = writeBanner(part.f, p.cfg)
+		if err != nil {
+			return err
+		}
+		err = part.writer.Write(p.row(p.header))
+		if err != nil {
+			return err
+		}
+	} else if part.f == nil {
+		err := p.openFile(part, os.O_WRONLY|os.O_APPEND)
+		if err != nil {
+			return err
+		}
+	} else {
+		p.open.MoveToFront(part.lru)
+	}
+	return part.writer.Write(p.row(record))
+}
+
+// openFile opens the temp file of part with flag, first closing the least
+// recently written partition if maxOpenPartitions are open
+func (p *partitionWriter) openFile(part *partition, flag int) error {
+	if p.open.Len() >= maxOpenPartitions {
+		err := p.closeFile(p.open.Back().Value.(*partition))
+		if err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(part.filename+".tmp", flag, 0755)
+	if err != nil {
+		return err
+	}
+	part.f = f
+	part.writer = newCSVWriter(f, p.cfg)
+	part.lru = p.open.PushFront(part)
+	return nil
+}
+
+// closeFile flushes and closes the temp file of an open partition
+func (p *partitionWriter) closeFile(part *partition) error {
+	p.open.Remove(part.lru)
+	part.writer.Flush()
+	err := part.writer.Error()
+	if cerr := part.f.Close(); err == nil {
+		err = cerr
+	}
+	part.f, part.writer, part.lru = nil, nil, nil
+	return err
+}
+
+// Close flushes every partition and moves it into place
+func (p *partitionWriter) Close() error {
+	for p.open.Len() > 0 {
+		err := p.closeFile(p.open.Front().Value.(*partition))
+		if err != nil {
+			return err
+		}
+	}
+	for _, part := range p.partitions {
+		err := os.Rename(part.filename+".tmp", part.filename)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Abort closes and removes every partition written so far
+func (p *partitionWriter) Abort() {
+	for _, part := range p.partitions {
+		if part.f != nil {
+			part.f.Close()
+		}
+		os.Remove(part.filename + ".tmp")
+	}
+}
+
+// writePartitions writes cfg.count entries partitioned by cfg.partitionBy
+func writePartitions(cfg genCfg, selected []int) (int, error) {
+	column := -1
+	for i, name := range selectValues(columnNames(columns), selected) {
+		if name == cfg.partitionBy {
+			column = i
+		}
+	}
+	if column == -1 {
+		return 0, fmt.Errorf("partition-by column %q is not in the output", cfg.partitionBy)
+	}
+	if cfg.partitionDrop && len(selected) == 1 {
+		return 0, fmt.Errorf("partition-drop would leave no columns")
+	}
+
+	dir := strings.TrimSuffix(cfg.filename, filepath.Ext(cfg.filename))
+	writer := newPartitionWriter(cfg, dir, column, cfg.partitionDrop)
+	written, err := writeEntries(writer, cfg, selected)
+	if err != nil {
+		writer.Abort()
+		return written, err
+	}
+	err = writer.Close()
+	if err != nil {
+		writer.Abort()
+	}
+	return written, err
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPartitions(t *testing.T) {
+	tests := []struct {
+		name   string
+		column string
+		args   []string
+		// minPartitions is the least number of partitions written
+		minPartitions int
+		wantDefault   bool
+	}{
+		{"low cardinality", "Card Type Code", nil, 2, false},
+		// more values than maxOpenPartitions, so partitions are closed and reopened
+		{"high cardinality", "Card PIN", nil, maxOpenPartitions + 1, false},
+		{"empty values", "Issuing Bank", []string{"-unknown-issuer-rate", "0.3"}, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"-count", "3000", "-partition-by", tt.column}, tt.args...)
+			dir := strings.TrimSuffix(generateFile(t, args...), ".csv")
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) < tt.minPartitions {
+				t.Fatalf("got %d partitions, want at least %d", len(entries), tt.minPartitions)
+			}
+			rows := 0
+			for _, e := range entries {
+				value := strings.TrimPrefix(e.Name(), tt.column+"=")
+				if value == e.Name() {
+					t.Fatalf("partition directory %q is not named %s=value", e.Name(), tt.column)
+				}
+				if value == defaultPartition {
+					value = ""
+				}
+				files, err := os.ReadDir(filepath.Join(dir, e.Name()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(files) != 1 || files[0].Name() != "part-0.csv" {
+					t.Fatalf("partition %s has files %v, want only part-0.csv", e.Name(), files)
+				}
+				_, part := readCSV(t, filepath.Join(dir, e.Name(), "part-0.csv"))
+				for _, row := range part {
+					if row[tt.column] != value {
+						t.Errorf("partition %s has a row with %s %q", e.Name(), tt.column, row[tt.column])
+					}
+				}
+				rows += len(part)
+			}
+			if rows != 3000 {
+				t.Errorf("partitions hold %d rows, want 3000", rows)
+			}
+			if tt.wantDefault {
+				if _, err := os.Stat(filepath.Join(dir, tt.column+"="+defaultPartition)); err != nil {
+					t.Errorf("no default partition for empty values: %v", err)
+				}
+			}
+		})
+	}
+}
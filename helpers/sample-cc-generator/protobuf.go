@@ -0,0 +1,157 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Protobuf wire types used by the protobuf encoder
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// protoFieldNumber returns the field number of column i in the Entry message.
+// Columns are only ever appended, so numbers stay stable across releases.
+func protoFieldNumber(i int) int {
+	return i + 1
+}
+
+// snakeName returns name in snake case, e.g. card_holders_name, for proto
+// fields and BigQuery columns
+func snakeName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(strings.ReplaceAll(name, "'", "")), func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	})
+	return strings.Join(words, "_")
+}
+
+// protoType returns the protobuf scalar type of a column type
+func protoType(kind string) string {
+	switch kind {
+	case typeInteger:
+		return "int64"
+	case typeBoolean:
+		return "bool"
+	case typeFloat:
+		return "double"
+	default:
+		return "string"
+	}
+}
+
+// protobufEncoder writes rows as varint length-delimited Entry messages, as
+// described by the .proto written with -proto-schema. Empty values are left
+// out like proto3 defaults.
+type protobufEncoder struct {
+	w       *bufio.Writer
+	columns []int
+	msg     []byte
+	varint  [binary.MaxVarintLen64]byte
+}
+
+func (p *protobufEncoder) WriteHeader(header []string) error {
+	for _, name := range header {
+		i := columnIndex(name)
+		if i == -1 {
+			return fmt.Errorf("no protobuf field for column %q", name)
+		}
+		p.columns = append(p.columns, i)
+	}
+	return nil
+}
+
+func (p *protobufEncoder) appendVarint(b []byte, v uint64) []byte {
+	n := binary.PutUvarint(p.varint[:], v)
+	return append(b, p.varint[:n]...)
+}
+
+func (p *protobufEncoder) WriteRow(values []string) error {
+	p.msg = p.msg[:0]
+	for j, v := range values {
+		if v == "" {
+			continue
+		}
+		i := p.columns[j]
+		num := uint64(protoFieldNumber(i))
+		switch columns[i].kind {
+		case typeInteger:
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("column %q: %v", columns[i].name, err)
+			}
+			p.msg = p.appendVarint(p.msg, num<<3|wireVarint)
+			p.msg = p.appendVarint(p.msg, uint64(n))
+		case typeFloat:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("column %q: %v", columns[i].name, err)
+			}
+			p.msg = p.appendVarint(p.msg, num<<3|wireFixed64)
+			p.msg = append(p.msg, make([]byte, 8)...)
+			binary.LittleEndian.PutUint64(p.msg[len(p.msg)-8:], math.Float64bits(f))
+		case typeBoolean:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("column %q: %v", columns[i].name, err)
+			}
+			if b {
+				p.msg = p.appendVarint(p.msg, num<<3|wireVarint)
+				p.msg = append(p.msg, 1)
+			}
+		default:
+			p.msg = p.appendVarint(p.msg, num<<3|wireBytes)
+			p.msg = p.appendVarint(p.msg, uint64(len(v)))
+			p.msg = append(p.msg, v...)
+		}
+	}
+	n := binary.PutUvarint(p.varint[:], uint64(len(p.msg)))
+	p.w.Write(p.varint[:n])
+	_, err := p.w.Write(p.msg)
+	return err
+}
+
+func (p *protobufEncoder) Flush() error {
+	return p.w.Flush()
+}
+
+// writeProtoSchema writes the proto3 definition of the Entry message of the
+// selected columns
+func writeProtoSchema(filename string, selected []int) error {
+	return writeFile(filename, func(w io.Writer) error {
+		var b strings.Builder
+		b.WriteString("syntax = \"proto3\";\n\npackage sample_cc_generator;\n\n")
+		b.WriteString("// Entry is a generated row, written varint length-delimited by -format protobuf\n")
+		b.WriteString("message Entry {\n")
+		for _, i := range selected {
+			c := columns[i]
+			if c.description != "" {
+				fmt.Fprintf(&b, "  // %s\n", c.description)
+			}
+			fmt.Fprintf(&b, "  %s %s = %d;\n", protoType(c.kind), snakeName(c.name), protoFieldNumber(i))
+		}
+		b.WriteString("}\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	})
+}
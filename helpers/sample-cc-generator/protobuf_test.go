@@ -0,0 +1,153 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	gofakeit "github.com/brianvoe/gofakeit/v6"
+)
+
+// readEntries decodes the length-delimited messages of a protobuf output,
+// keyed by field number with values formatted like the csv
+func readEntries(t *testing.T, filename string) []map[int]string {
+	t.Helper()
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var entries []map[int]string
+	for {
+		size, err := binary.ReadUvarint(r)
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := make([]byte, size)
+		_, err = io.ReadFull(r, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := map[int]string{}
+		for len(msg) > 0 {
+			key, n := binary.Uvarint(msg)
+			msg = msg[n:]
+			num := int(key >> 3)
+			switch key & 7 {
+			case wireVarint:
+				v, n := binary.Uvarint(msg)
+				msg = msg[n:]
+				if columns[num-1].kind == typeBoolean {
+					entry[num] = strconv.FormatBool(v != 0)
+				} else {
+					entry[num] = strconv.FormatInt(int64(v), 10)
+				}
+			case wireFixed64:
+				entry[num] = strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(msg)), 'f', -1, 64)
+				msg = msg[8:]
+			case wireBytes:
+				l, n := binary.Uvarint(msg)
+				entry[num] = string(msg[n : n+int(l)])
+				msg = msg[n+int(l):]
+			default:
+				t.Fatalf("field %d has unexpected wire type %d", num, key&7)
+			}
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func TestProtobuf(t *testing.T) {
+	args := []string{"-count", "200", "-seed", "8", "-ach", "-dispute-rate", "0.5",
+		"-allow-negative-limit", "-negative-limit-rate", "0.3", "-unknown-issuer-rate", "0.2"}
+	header, rows := generateCSV(t, args...)
+
+	dir := t.TempDir()
+	filename, schema := filepath.Join(dir, "data.pb"), filepath.Join(dir, "entry.proto")
+	cfg := parseArgs(t, append([]string{"-filename", filename, "-format", "protobuf", "-proto-schema", schema}, args...)...)
+	// a column without a description
+	err := registerField(field{"Extra", func(faker *gofakeit.Faker, row *Context) string { return "x" }}, typeString, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := readEntries(t, filename)
+	if len(entries) != len(rows) {
+		t.Fatalf("got %d messages, want %d", len(entries), len(rows))
+	}
+	for i, entry := range entries {
+		for _, name := range header {
+			c := columnIndex(name)
+			got, ok := entry[protoFieldNumber(c)]
+			// proto3 leaves out empty strings and false
+			if !ok && columns[c].kind == typeBoolean {
+				got = "false"
+			}
+			if want := rows[i][name]; got != want {
+				t.Errorf("message %d: %s = %q, want %q", i, name, got, want)
+			}
+		}
+	}
+
+	b, err := os.ReadFile(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range header {
+		c := columnIndex(name)
+		field := regexp.MustCompile(`\n  ` + protoType(columns[c].kind) + ` ` + snakeName(name) + ` = ` + strconv.Itoa(protoFieldNumber(c)) + `;\n`)
+		if !field.Match(b) {
+			t.Errorf("schema has no field for %s:\n%s", name, b)
+		}
+	}
+	if !strings.Contains(string(b), ";\n  string extra = ") || strings.Contains(string(b), " \n") {
+		t.Errorf("schema has an empty comment for a column without a description:\n%s", b)
+	}
+}
+
+func TestProtoFieldNumbersStable(t *testing.T) {
+	// field numbers are column positions, so new columns must be appended
+	want := []string{
+		"Card Type Code", "Card Type Full Name", "Issuing Bank", "Card Number", "Card Holder's Name",
+		"CVV/CVV2", "Issue Date", "Expiry Date", "Billing Date", "Card PIN", "Credit Limit",
+		"Routing Number", "Account Number", "Customer UUID", "First Name", "Last Name", "Due Date",
+		"Disputed", "Balance", "Is Duplicate", "Parent Account ID", "Parent Credit Limit",
+		"Notes", "Notes PII", "Last Four", "Residency Country", "Nationality",
+	}
+	if len(builtinColumns) < len(want) {
+		t.Fatalf("got %d columns, want at least %d", len(builtinColumns), len(want))
+	}
+	for i, name := range want {
+		if got := builtinColumns[i].name; got != name {
+			t.Errorf("field %d is %s, want %s", protoFieldNumber(i), got, name)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces sensitive values in log messages
+const redacted = "[REDACTED]"
+
+var (
+	// panPattern matches 12-19 digits, optionally grouped by spaces or dashes
+	panPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){11,18}\b`)
+	// cvvPattern matches 3-4 digits labelled as a cvv or pin, keeping the label
+	cvvPattern = regexp.MustCompile(`(?i)\b(cvv2?|cvc|pin)(\W{1,3})\d{3,4}\b`)
+	// base64Pattern matches runs of 24 or more base64 characters
+	base64Pattern = regexp.MustCompile(`[A-Za-z0-9+/]{24,}={0,2}`)
+	// base64Mixed keeps base64Pattern from matching words, real blobs mix upper
+	// case, lower case and digits
+	base64Mixed = []*regexp.Regexp{regexp.MustCompile(`[A-Z]`), regexp.MustCompile(`[a-z]`), regexp.MustCompile(`[0-9]`)}
+)
+
+// redact masks anything in s resembling a card number, cvv or base64 blob
+func redact(s string) string {
+	s = panPattern.ReplaceAllString(s, redacted)
+	s = cvvPattern.ReplaceAllString(s, "${1}${2}"+redacted)
+	return base64Pattern.ReplaceAllStringFunc(s, func(m string) string {
+		if !isBase64(m) {
+			return m
+		}
+		return redacted
+	})
+}
+
+// isBase64 reports whether a base64Pattern match has the shape of a base64
+// blob rather than a file path like /tmp/Users/Alice/Datasets2024/out: its
+// length is a multiple of 4 and, if it has slashes, it is padded with =
+func isBase64(m string) bool {
+	if len(m)%4 != 0 || (strings.Contains(m, "/") && !strings.HasSuffix(m, "=")) {
+		return false
+	}
+	for _, re := range base64Mixed {
+		if !re.MatchString(m) {
+			return false
+		}
+	}
+	return true
+}
+
+// redactWriter redacts each log message before writing it to w
+type redactWriter struct {
+	w io.Writer
+}
+
+func (r redactWriter) Write(p []byte) (int, error) {
+	_, err := io.WriteString(r.w, redact(string(p)))
+	// report the original length, the log package treats a short write as an error
+	return len(p), err
+}
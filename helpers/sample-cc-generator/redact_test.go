@@ -0,0 +1,63 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"card 4242424242424242 declined", "card [REDACTED] declined"},
+		{"card 4242-4242-4242-4242 declined", "card [REDACTED] declined"},
+		{"card 3782 822463 10005", "card [REDACTED]"},
+		{"cvv: 123 and PIN=9876", "cvv: [REDACTED] and PIN=[REDACTED]"},
+		{"ciphertext AbCdEf0123456789GhIjKlMnOpQrSt== failed", "ciphertext [REDACTED] failed"},
+		{"wrote 100000 entries to /tmp/cards/output/directory/data.csv", "wrote 100000 entries to /tmp/cards/output/directory/data.csv"},
+		{"key AbCd/f0123456789GhIjKlMnOpQrSt== rejected", "key [REDACTED] rejected"},
+		{"wrote 3 entries to /tmp/Users/Alice/Datasets2024/cards/out.csv", "wrote 3 entries to /tmp/Users/Alice/Datasets2024/cards/out.csv"},
+		{"open /tmp/Users/Alice/Datasets2024/cards/out.csv.tmp: no such file or directory", "open /tmp/Users/Alice/Datasets2024/cards/out.csv.tmp: no such file or directory"},
+		{"wrote 3 entries to /tmp/Users/Alice/Datasets2024/cards/outs.csv", "wrote 3 entries to /tmp/Users/Alice/Datasets2024/cards/outs.csv"},
+		{"seed 1 count 10", "seed 1 count 10"},
+	}
+	for _, tt := range tests {
+		if got := redact(tt.in); got != tt.want {
+			t.Errorf("redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	logger := log.New(redactWriter{&buf}, "", 0)
+	logger.Printf("invalid card number %s", "5555555555554444")
+	if got := buf.String(); strings.Contains(got, "5555") || !strings.Contains(got, redacted) {
+		t.Errorf("logged %q, want the card number redacted", got)
+	}
+
+	// main redacts its own log messages unless disabled
+	out, err := runMain(t, "-count 1 -names-file 4242424242424242.txt")
+	if err == nil || strings.Contains(out, "4242") || !strings.Contains(out, redacted) {
+		t.Errorf("got error %v, want the card number redacted:\n%s", err, out)
+	}
+	out, _ = runMain(t, "-count 1 -names-file 4242424242424242.txt -redact-logs=false")
+	if !strings.Contains(out, "4242424242424242") {
+		t.Errorf("redact-logs=false redacted the log:\n%s", out)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"hash/fnv"
+	"io"
+	"math"
+	"math/bits"
+)
+
+const (
+	// maxExactDistinct is the number of distinct values counted exactly per column,
+	// past it the count is estimated with a HyperLogLog sketch
+	maxExactDistinct = 100000
+	// hllPrecision is the number of hash bits selecting a HyperLogLog register
+	hllPrecision = 14
+)
+
+// hyperLogLog estimates the number of distinct values added to it
+type hyperLogLog struct {
+	registers []uint8
+}
+
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{registers: make([]uint8, 1<<hllPrecision)}
+}
+
+func (h *hyperLogLog) add(value string) {
+	f := fnv.New64a()
+	f.Write([]byte(value))
+	x := mix64(f.Sum64())
+	i := x >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > h.registers[i] {
+		h.registers[i] = rank
+	}
+}
+
+// mix64 is the murmur3 finalizer. fnv barely changes the high bits between
+// short values like sequential numbers, and those bits select the register.
+func mix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+func (h *hyperLogLog) estimate() uint64 {
+	m := float64(len(h.registers))
+	sum, zeros := 0.0, 0
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	// linear counting is more accurate for small cardinalities
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(e + 0.5)
+}
+
+// columnStats accumulates the statistics of a single column
+type columnStats struct {
+	name     string
+	nonNull  int
+	distinct map[string]bool
+	sketch   *hyperLogLog
+	checksum hash.Hash
+}
+
+func (c *columnStats) add(value string) {
+	// every value, empty or not, is part of the checksum
+	c.checksum.Write([]byte(value))
+	c.checksum.Write([]byte{0})
+	if value == "" {
+		return
+	}
+	c.nonNull++
+	c.sketch.add(value)
+	if c.distinct != nil {
+		c.distinct[value] = true
+		if len(c.distinct) > maxExactDistinct {
+			c.distinct = nil
+		}
+	}
+}
+
+// statsWriter collects per column statistics of the rows written through it
+type statsWriter struct {
+	writer  rowWriter
+	rows    int
+	columns []*columnStats
+}
+
+func newStatsWriter(writer rowWriter, header []string) *statsWriter {
+	s := &statsWriter{writer: writer}
+	for _, name := range header {
+		s.columns = append(s.columns, &columnStats{
+			name:     name,
+			distinct: map[string]bool{},
+			sketch:   newHyperLogLog(),
+			checksum: sha256.New(),
+		})
+	}
+	return s
+}
+
+func (s *statsWriter) Write(record []string) error {
+	s.rows++
+	for i, v := range record {
+		s.columns[i].add(v)
+	}
+	return s.writer.Write(record)
+}
+
+// stats of a run
+type stats struct {
+	Rows              int                `json:"rows"`
+	FakerVersion      string             `json:"faker_version"`
+	GenerationVersion int                `json:"generation_version"`
+	Encoding          string             `json:"encoding"`
+	Columns           []columnStatsEntry `json:"columns"`
+}
+
+// stats of a column
+type columnStatsEntry struct {
+	Name          string `json:"name"`
+	NonNull       int    `json:"non_null"`
+	Distinct      uint64 `json:"distinct"`
+	DistinctExact bool   `json:"distinct_exact"`
+	Checksum      string `json:"checksum"`
+}
+
+func (s *statsWriter) stats() stats {
+	st := stats{Rows: s.rows, FakerVersion: fakerVersion()}
+	for _, c := range s.columns {
+		e := columnStatsEntry{
+			Name:     c.name,
+			NonNull:  c.nonNull,
+			Checksum: "sha256:" + hex.EncodeToString(c.checksum.Sum(nil)),
+		}
+		if c.distinct != nil {
+			e.Distinct, e.DistinctExact = uint64(len(c.distinct)), true
+		} else {
+			e.Distinct = c.sketch.estimate()
+		}
+		st.Columns = append(st.Columns, e)
+	}
+	return st
+}
+
+// writeStats writes st as json to filename
+func writeStats(filename string, st stats) error {
+	return writeFile(filename, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(st)
+	})
+}
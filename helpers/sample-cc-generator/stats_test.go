@@ -0,0 +1,124 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// discardWriter is a rowWriter dropping every row
+type discardWriter struct{}
+
+func (discardWriter) Write(record []string) error { return nil }
+
+func TestStatsWriter(t *testing.T) {
+	s := newStatsWriter(discardWriter{}, []string{"a", "b"})
+	for _, record := range [][]string{{"x", ""}, {"y", "1"}, {"x", ""}} {
+		err := s.Write(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checksum := func(values ...string) string {
+		h := sha256.New()
+		for _, v := range values {
+			h.Write([]byte(v))
+			h.Write([]byte{0})
+		}
+		return "sha256:" + hex.EncodeToString(h.Sum(nil))
+	}
+	want := []columnStatsEntry{
+		{Name: "a", NonNull: 3, Distinct: 2, DistinctExact: true, Checksum: checksum("x", "y", "x")},
+		{Name: "b", NonNull: 1, Distinct: 1, DistinctExact: true, Checksum: checksum("", "1", "")},
+	}
+	st := s.stats()
+	if st.Rows != 3 {
+		t.Errorf("rows = %d, want 3", st.Rows)
+	}
+	for i, got := range st.Columns {
+		if got != want[i] {
+			t.Errorf("column %d stats = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestHyperLogLog(t *testing.T) {
+	for _, n := range []int{10, 1000, 200000} {
+		h := newHyperLogLog()
+		for i := 0; i < n; i++ {
+			h.add(strconv.Itoa(i))
+		}
+		if got := h.estimate(); math.Abs(float64(got)-float64(n)) > 0.02*float64(n)+1 {
+			t.Errorf("estimate of %d distinct values = %d", n, got)
+		}
+	}
+}
+
+func TestStatsGenerationVersion(t *testing.T) {
+	statsFile := filepath.Join(t.TempDir(), "stats.json")
+	generateFile(t, "-count", "5", "-generation-version", "2", "-stats", statsFile)
+	b, err := os.ReadFile(statsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var st stats
+	err = json.Unmarshal(b, &st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.GenerationVersion != generationStreams {
+		t.Errorf("generation version = %d, want %d", st.GenerationVersion, generationStreams)
+	}
+}
+
+func TestStatsFile(t *testing.T) {
+	statsFile := filepath.Join(t.TempDir(), "stats.json")
+	header, rows := generateCSV(t, "-count", "20", "-unknown-issuer-rate", "0.3", "-stats", statsFile)
+	b, err := os.ReadFile(statsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var st stats
+	err = json.Unmarshal(b, &st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Rows != 20 || len(st.Columns) != len(header) {
+		t.Fatalf("stats of %d rows and %d columns, want 20 rows and %d columns", st.Rows, len(st.Columns), len(header))
+	}
+	if st.FakerVersion != fakerVersion() || st.GenerationVersion != generationShared || st.Encoding != encodingUTF8 {
+		t.Errorf("stats of faker %s, generation version %d and encoding %s, want %s, %d and %s",
+			st.FakerVersion, st.GenerationVersion, st.Encoding, fakerVersion(), generationShared, encodingUTF8)
+	}
+	for i, c := range st.Columns {
+		nonNull, distinct := 0, map[string]bool{}
+		for _, row := range rows {
+			if v := row[header[i]]; v != "" {
+				nonNull++
+				distinct[v] = true
+			}
+		}
+		if c.Name != header[i] || c.NonNull != nonNull || c.Distinct != uint64(len(distinct)) || !c.DistinctExact {
+			t.Errorf("stats of %s = %+v, want %d non-null and %d distinct values", header[i], c, nonNull, len(distinct))
+		}
+	}
+}
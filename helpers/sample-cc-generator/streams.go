@@ -0,0 +1,78 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+
+	gofakeit "github.com/brianvoe/gofakeit/v6"
+)
+
+const (
+	// generationShared draws every column from a single faker seeded with the run seed
+	generationShared = 1
+	// generationStreams draws each column from its own faker seeded from the run
+	// seed and column name, so adding, removing or reordering columns, or a change
+	// in the draws of one column, leaves the values of the other columns unchanged
+	generationStreams = 2
+)
+
+// fakers hands out the faker each column draws from
+type fakers struct {
+	seed    int64
+	shared  *gofakeit.Faker
+	columns map[string]*gofakeit.Faker
+	// constraints is the stream card number constraints draw from, with every
+	// generation version, so they only change the card number
+	constraints *gofakeit.Faker
+	// customers is the card holder pool of the run
+	customers []customer
+	// parent is the account of the current entry in an accounts-hierarchy
+	// run, parents counts the accounts started
+	parent  *parentAccount
+	parents int
+}
+
+func newFakers(cfg genCfg) *fakers {
+	constraints := gofakeit.New(columnSeed(cfg.seed, "Card Number constraints"))
+	if cfg.generationVersion == generationStreams {
+		return &fakers{seed: cfg.seed, columns: map[string]*gofakeit.Faker{}, constraints: constraints, customers: newCustomers(cfg)}
+	}
+	return &fakers{seed: cfg.seed, shared: gofakeit.New(cfg.seed), constraints: constraints, customers: newCustomers(cfg)}
+}
+
+// column returns the faker for the named column
+func (f *fakers) column(name string) *gofakeit.Faker {
+	if f.columns == nil {
+		return f.shared
+	}
+	faker, ok := f.columns[name]
+	if !ok {
+		faker = gofakeit.New(columnSeed(f.seed, name))
+		f.columns[name] = faker
+	}
+	return faker
+}
+
+// columnSeed derives the seed of a column's faker from the run seed
+func columnSeed(seed int64, name string) int64 {
+	h := fnv.New64a()
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(seed))
+	h.Write(b[:])
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,119 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	gofakeit "github.com/brianvoe/gofakeit/v6"
+)
+
+func TestGenerationStreams(t *testing.T) {
+	args := []string{"-count", "100", "-seed", "7", "-generation-version", "2"}
+	header, before := generateCSV(t, args...)
+
+	// add optional columns and a registered column drawing from its own stream
+	filename := filepath.Join(t.TempDir(), "data.csv")
+	cfg := parseArgs(t, append([]string{"-filename", filename, "-ach", "-dispute-rate", "0.5", "-balance"}, args...)...)
+	err := registerField(field{"Lucky Number", func(faker *gofakeit.Faker, row *Context) string {
+		return faker.DigitN(8)
+	}}, typeString, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, after := readCSV(t, filename)
+
+	if len(after) != len(before) {
+		t.Fatalf("got %d rows, want %d", len(after), len(before))
+	}
+	for i := range before {
+		for _, name := range header {
+			if after[i][name] != before[i][name] {
+				t.Errorf("row %d: %s changed from %q to %q when columns were added", i, name, before[i][name], after[i][name])
+			}
+		}
+	}
+}
+
+func TestGenerationStreamsReordered(t *testing.T) {
+	args := []string{"-count", "100", "-seed", "11", "-generation-version", "2", "-ach"}
+	order, before := generateCSV(t, args...)
+
+	filename := filepath.Join(t.TempDir(), "data.csv")
+	cfg := parseArgs(t, append([]string{"-filename", filename}, args...)...)
+	// reverse the columns, and the generators that don't read other columns
+	for i, j := 0, len(columns)-1; i < j; i, j = i+1, j-1 {
+		columns[i], columns[j] = columns[j], columns[i]
+	}
+	independent := map[string]bool{"Billing Date": true, "Card PIN": true, "Credit Limit": true, "Routing Number": true, "Account Number": true}
+	var idx []int
+	for i, g := range generators {
+		if independent[g.Name()] {
+			idx = append(idx, i)
+		}
+	}
+	for i, j := 0, len(idx)-1; i < j; i, j = i+1, j-1 {
+		generators[idx[i]], generators[idx[j]] = generators[idx[j]], generators[idx[i]]
+	}
+	err := run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, after := readCSV(t, filename)
+	if header[0] != order[len(order)-1] {
+		t.Fatalf("header %q isn't in the reversed column order", header)
+	}
+
+	for i := range before {
+		for _, name := range header {
+			if after[i][name] != before[i][name] {
+				t.Errorf("row %d: %s changed from %q to %q when columns were reordered", i, name, before[i][name], after[i][name])
+			}
+		}
+	}
+}
+
+func TestNormalizeLengthStream(t *testing.T) {
+	for _, version := range []string{"1", "2"} {
+		t.Run("generation version "+version, func(t *testing.T) {
+			args := []string{"-count", "500", "-seed", "5", "-generation-version", version, "-emit-last-four", "-ach", "-balance"}
+			header, before := generateCSV(t, args...)
+			_, after := generateCSV(t, append(args, "-normalize-length", "16")...)
+			changed := 0
+			for i := range before {
+				if after[i]["Card Number"] != before[i]["Card Number"] {
+					changed++
+				}
+				for _, name := range header {
+					if name == "Card Number" || name == "Last Four" {
+						continue
+					}
+					if after[i][name] != before[i][name] {
+						t.Errorf("row %d: %s changed from %q to %q with normalize-length", i, name, before[i][name], after[i][name])
+					}
+				}
+			}
+			// amex, diners and 19 digit cards are normalized
+			if changed == 0 {
+				t.Error("normalize-length changed no card number")
+			}
+		})
+	}
+}
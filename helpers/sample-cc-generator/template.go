@@ -0,0 +1,72 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+)
+
+// rowTemplate holds column values to use verbatim instead of generating them
+type rowTemplate map[string]string
+
+// loadTemplates reads csv row templates from filename, or stdin if filename is "-".
+// The header names the columns being set, empty values are generated as usual.
+func loadTemplates(filename string, cfg genCfg) ([]rowTemplate, error) {
+	var r io.Reader = os.Stdin
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	reader := csv.NewReader(r)
+	// skip banner lines so generated files can be used as templates
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("template %s needs a header and at least one row", filename)
+	}
+	header := records[0]
+	for _, name := range header {
+		i := columnIndex(name)
+		if i == -1 {
+			return nil, fmt.Errorf("unknown column %q in template %s", name, filename)
+		}
+		if !cfg.enabled(columns[i].option) {
+			return nil, fmt.Errorf("column %q in template %s is not enabled", name, filename)
+		}
+	}
+
+	templates := make([]rowTemplate, 0, len(records)-1)
+	for _, record := range records[1:] {
+		t := make(rowTemplate, len(header))
+		for i, name := range header {
+			if record[i] != "" {
+				t[name] = record[i]
+			}
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
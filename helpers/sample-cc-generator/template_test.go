@@ -0,0 +1,91 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestTemplates(t *testing.T) {
+	dir := t.TempDir()
+	template := writeLines(t, dir, "template.csv",
+		"Card Holder's Name,Credit Limit",
+		"Ada Lovelace,",
+		"Alan Turing,5000",
+		",7000",
+	)
+	_, rows := generateCSV(t, "-template-file", template)
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want one per template row", len(rows))
+	}
+	tests := []struct {
+		name  string
+		limit string
+	}{
+		{"Ada Lovelace", ""},
+		{"Alan Turing", "5000"},
+		{"", "7000"},
+	}
+	for i, tt := range tests {
+		row := rows[i]
+		if tt.name != "" && row["Card Holder's Name"] != tt.name {
+			t.Errorf("row %d: name = %q, want %q", i, row["Card Holder's Name"], tt.name)
+		}
+		if tt.limit != "" && row["Credit Limit"] != tt.limit {
+			t.Errorf("row %d: limit = %q, want %q", i, row["Credit Limit"], tt.limit)
+		}
+		// empty template values are generated
+		if row["Card Holder's Name"] == "" || row["Credit Limit"] == "" {
+			t.Errorf("row %d: empty template value wasn't generated: %v", i, row)
+		}
+		if number := row["Card Number"]; !luhnValid(number) {
+			t.Errorf("row %d: generated card number %q is not Luhn valid", i, number)
+		}
+	}
+}
+
+func TestTemplatesTypedJSON(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeLines(t, dir, "schema.json",
+		`[{"name": "score", "type": "FLOAT64"}, {"name": "credit_limit", "type": "INT64"}]`)
+	template := writeLines(t, dir, "template.csv",
+		"score,credit_limit",
+		".5,007",
+		"0x1p3,+5",
+		"NaN,-0",
+		"Inf,12",
+	)
+	b, err := os.ReadFile(generateFile(t, "-from-bq-schema", schema, "-template-file", template, "-format", "json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []map[string]interface{}
+	err = json.Unmarshal(b, &rows)
+	if err != nil {
+		t.Fatalf("output isn't valid json: %v\n%s", err, b)
+	}
+	want := []map[string]interface{}{
+		{"score": 0.5, "credit_limit": 7.0},
+		{"score": 8.0, "credit_limit": 5.0},
+		{"score": "NaN", "credit_limit": 0.0},
+		{"score": "Inf", "credit_limit": 12.0},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got rows %v, want %v", rows, want)
+	}
+}
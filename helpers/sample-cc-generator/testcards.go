@@ -0,0 +1,101 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+
+	gofakeit "github.com/brianvoe/gofakeit/v6"
+)
+
+// testCard is a card number published by a payment gateway for its sandbox
+type testCard struct {
+	network string
+	number  string
+}
+
+// testCards are the published test card numbers of each gateway. Sandboxes
+// only accept these exact numbers, so numbers aren't generated from their BINs.
+var testCards = map[string][]testCard{
+	"stripe": {
+		{"Visa", "4242424242424242"},
+		{"Visa", "4000056655665556"},
+		{"Mastercard", "5555555555554444"},
+		{"Mastercard", "2223003122003222"},
+		{"Mastercard", "5200828282828210"},
+		{"Mastercard", "5105105105105100"},
+		{"American Express", "378282246310005"},
+		{"American Express", "371449635398431"},
+		{"Discover", "6011111111111117"},
+		{"Discover", "6011000990139424"},
+		{"Diners Club", "3056930009020004"},
+		{"Diners Club", "36227206271667"},
+		{"JCB", "3566002020360505"},
+		{"UnionPay", "6200000000000005"},
+	},
+	"adyen": {
+		{"Visa", "4111111145551142"},
+		{"Visa", "4988438843884305"},
+		{"Visa", "4166676667666746"},
+		{"Visa", "4646464646464644"},
+		{"Visa", "4000620000000007"},
+		{"Visa", "4000060000000006"},
+		{"Mastercard", "5555341244441115"},
+		{"Mastercard", "2222400070000005"},
+		{"Mastercard", "5577000055770004"},
+		{"Mastercard", "5136333333333335"},
+		{"Mastercard", "5454545454545454"},
+		{"American Express", "370000000000002"},
+		{"Diners Club", "36006666333344"},
+		{"Discover", "6011601160116611"},
+		{"JCB", "3569990010095841"},
+	},
+	"braintree": {
+		{"Visa", "4111111111111111"},
+		{"Visa", "4005519200000004"},
+		{"Visa", "4009348888881881"},
+		{"Visa", "4012000033330026"},
+		{"Visa", "4012000077777777"},
+		{"Visa", "4012888888881881"},
+		{"Visa", "4217651111111119"},
+		{"Visa", "4500600000000061"},
+		{"Mastercard", "5555555555554444"},
+		{"Mastercard", "2223000048400011"},
+		{"American Express", "378282246310005"},
+		{"American Express", "371449635398431"},
+		{"Discover", "6011111111111117"},
+		{"JCB", "3530111333300000"},
+		{"Maestro", "6304000000000000"},
+		{"Diners Club", "36259600000004"},
+	},
+}
+
+// testCardGateways returns the gateways with test card numbers
+func testCardGateways() []string {
+	names := make([]string, 0, len(testCards))
+	for name := range testCards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// gatewayCard picks a random test card of gateway. The cvv is left empty to be
+// generated with the network's length.
+func gatewayCard(faker *gofakeit.Faker, gateway string) *gofakeit.CreditCardInfo {
+	cards := testCards[gateway]
+	card := cards[faker.Number(0, len(cards)-1)]
+	return &gofakeit.CreditCardInfo{Type: card.network, Number: card.number}
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTestBins(t *testing.T) {
+	for _, gateway := range testCardGateways() {
+		t.Run(gateway, func(t *testing.T) {
+			networks := map[string]string{}
+			for _, c := range testCards[gateway] {
+				if !luhnValid(c.number) {
+					t.Errorf("published %s card %s isn't luhn valid", c.network, c.number)
+				}
+				networks[c.number] = c.network
+			}
+			_, rows := generateCSV(t, "-count", "500", "-test-bins", gateway)
+			used := map[string]bool{}
+			for i, row := range rows {
+				number := row["Card Number"]
+				network, ok := networks[number]
+				if !ok {
+					t.Fatalf("row %d: %s isn't a %s test card", i, number, gateway)
+				}
+				used[number] = true
+				if row["Card Type Full Name"] != network {
+					t.Errorf("row %d: %s is a %s card, not %s", i, number, network, row["Card Type Full Name"])
+				}
+				if n := cvvLength(network); len(row["CVV/CVV2"]) != n {
+					t.Errorf("row %d: %s cvv %q isn't %d digits", i, network, row["CVV/CVV2"], n)
+				}
+			}
+			if len(used) != len(networks) {
+				t.Errorf("500 rows used %d of the %d test cards", len(used), len(networks))
+			}
+		})
+	}
+}
+
+func TestDedupeCards(t *testing.T) {
+	// stripe has 14 test cards, so most entries start as duplicates
+	_, rows := generateCSV(t, "-count", "14", "-test-bins", "stripe", "-dedupe-cards")
+	seen := map[string]bool{}
+	for i, row := range rows {
+		if seen[row["Card Number"]] {
+			t.Errorf("row %d: duplicate card number %s", i, row["Card Number"])
+		}
+		seen[row["Card Number"]] = true
+	}
+	if len(seen) != 14 {
+		t.Errorf("got %d unique card numbers, want 14", len(seen))
+	}
+
+	cfg := parseArgs(t, "-filename", filepath.Join(t.TempDir(), "data.csv"), "-count", "15", "-test-bins", "stripe", "-dedupe-cards")
+	err := run(cfg)
+	if err == nil || !strings.Contains(err.Error(), "exhausted after 14 unique ones") {
+		t.Errorf("got error %v, want card numbers exhausted", err)
+	}
+}
+
+func TestMaxRegenTotal(t *testing.T) {
+	// collecting all 14 stripe cards takes about 30 regenerations
+	args := []string{"-count", "14", "-test-bins", "stripe", "-dedupe-cards"}
+	cfg := parseArgs(t, append([]string{"-filename", filepath.Join(t.TempDir(), "data.csv"), "-max-regen-total", "5"}, args...)...)
+	err := run(cfg)
+	if err == nil || !strings.Contains(err.Error(), "max-regen-total 5 exceeded") || !strings.Contains(err.Error(), "Card Number") {
+		t.Errorf("got error %v, want max-regen-total exceeded by Card Number", err)
+	}
+
+	_, rows := generateCSV(t, append(args, "-max-regen-total", "1000")...)
+	if len(rows) != 14 {
+		t.Errorf("got %d rows within the budget, want 14", len(rows))
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// defaultUUIDNamespace is the RFC 4122 DNS namespace
+const defaultUUIDNamespace = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+// parseUUID parses a uuid in its canonical 8-4-4-4-12 hex form
+func parseUUID(s string) ([16]byte, error) {
+	var u [16]byte
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("invalid uuid %q", s)
+	}
+	b, err := hex.DecodeString(strings.Replace(s, "-", "", -1))
+	if err != nil {
+		return u, fmt.Errorf("invalid uuid %q: %v", s, err)
+	}
+	copy(u[:], b)
+	return u, nil
+}
+
+// uuidV5 returns the RFC 4122 version 5 uuid of name within namespace
+func uuidV5(namespace [16]byte, name string) string {
+	h := sha1.New()
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+	var u [16]byte
+	copy(u[:], h.Sum(nil))
+	u[6] = (u[6] & 0x0f) | 0x50
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// customerIdentity canonicalizes the identity fields a customer uuid is derived
+// from. key tells apart customers with the same name.
+func customerIdentity(name, key string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " ")) + "\x00" + key
+}
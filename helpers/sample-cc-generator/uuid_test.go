@@ -0,0 +1,74 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestUUIDV5(t *testing.T) {
+	ns, err := parseUUID(defaultUUIDNamespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// python3 -c 'import uuid; print(uuid.uuid5(uuid.NAMESPACE_DNS, "python.org"))'
+	if got, want := uuidV5(ns, "python.org"), "886313e1-3b8a-5372-9b90-0c9aee199e5d"; got != want {
+		t.Errorf("uuidV5() = %s, want %s", got, want)
+	}
+
+	tests := []struct {
+		name      string
+		a, b      [2]string
+		wantEqual bool
+	}{
+		{"same identity", [2]string{"Ada Lovelace", "customer-1"}, [2]string{" ada  LOVELACE ", "customer-1"}, true},
+		{"same name, other customer", [2]string{"Ada Lovelace", "customer-1"}, [2]string{"Ada Lovelace", "customer-2"}, false},
+		{"other name", [2]string{"Ada Lovelace", "customer-1"}, [2]string{"Alan Turing", "customer-1"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := uuidV5(ns, customerIdentity(tt.a[0], tt.a[1]))
+			b := uuidV5(ns, customerIdentity(tt.b[0], tt.b[1]))
+			if (a == b) != tt.wantEqual {
+				t.Errorf("uuids %s and %s: equal = %v, want %v", a, b, a == b, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestCustomerUUIDs(t *testing.T) {
+	names := writeLines(t, t.TempDir(), "names.txt", "Ada Lovelace")
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		// every card has its own card holder, even with the same name
+		{"card holder per card", nil, 50},
+		{"customers pool", []string{"-num-customers", "3"}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rows := generateCSV(t, append([]string{"-count", "50", "-uuid", "-names-file", names}, tt.args...)...)
+			uuids := map[string]bool{}
+			for _, row := range rows {
+				uuids[row["Customer UUID"]] = true
+			}
+			if len(uuids) != tt.want {
+				t.Errorf("got %d distinct uuids, want %d", len(uuids), tt.want)
+			}
+		})
+	}
+}
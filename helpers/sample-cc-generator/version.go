@@ -0,0 +1,51 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// fakerModule is the module generated values are drawn with. Its version
+// changes the generated data, so it is recorded and can be pinned.
+const fakerModule = "github.com/brianvoe/gofakeit/v6"
+
+// fakerVersion returns the version of the linked faker module, or "unknown"
+// when the binary has no module information
+func fakerVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == fakerModule {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+// checkFakerVersion fails unless the linked faker version is the required one.
+// Any version is accepted when required is empty.
+func checkFakerVersion(required, linked string) error {
+	if required != "" && required != linked {
+		return fmt.Errorf("require-faker-version is %s but %s is linked at %s", required, fakerModule, linked)
+	}
+	return nil
+}
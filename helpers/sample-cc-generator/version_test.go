@@ -0,0 +1,45 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckFakerVersion(t *testing.T) {
+	tests := []struct {
+		required string
+		linked   string
+		wantErr  bool
+	}{
+		{"", "v6.9.0", false},
+		{"", "unknown", false},
+		{"v6.9.0", "v6.9.0", false},
+		{"v6.9.0", "v6.10.0", true},
+		{"v6.9.0", "unknown", true},
+	}
+	for _, tt := range tests {
+		err := checkFakerVersion(tt.required, tt.linked)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkFakerVersion(%q, %q) error = %v, want error %v", tt.required, tt.linked, err, tt.wantErr)
+		}
+	}
+
+	out, err := runMain(t, "-count 1 -require-faker-version v0.0.0")
+	if err == nil || !strings.Contains(out, "require-faker-version is v0.0.0") {
+		t.Errorf("got error %v, want the version mismatch:\n%s", err, out)
+	}
+}